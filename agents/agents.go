@@ -0,0 +1,204 @@
+// Package agents provides a registry of the santase agents that can be
+// chosen by name, for example from the command line.
+//
+// An agent is described by a specification of the form
+//
+//	name[:param=value,param=value...]
+//
+// for example "random" or "ismcts:c=5.4,budget=2s". Agents that run a
+// command take it before their parameters:
+//
+//	name:command[,param=value...]
+//
+// for example "exec:/path/to/bot,timeout=5s".
+package agents
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	santase "github.com/nvlbg/santase-ai"
+	"github.com/nvlbg/santase-ai/agents/ismcts"
+	"github.com/nvlbg/santase-ai/agents/random"
+
+	"github.com/nvlbg/santase-gui/agents/external"
+)
+
+// Constructor creates an agent from its parameters. The parameters should
+// be read with the typed getters of Params.
+type Constructor func(params *Params) (santase.Agent, error)
+
+// CommandConstructor creates an agent that runs the passed command from
+// the command and its parameters.
+type CommandConstructor func(command string, params *Params) (santase.Agent, error)
+
+type definition struct {
+	description string
+	constructor Constructor
+
+	// commandConstructor is set instead of constructor for agents that
+	// take a command
+	commandConstructor CommandConstructor
+
+	// timed is whether the agent thinks for a fixed time per move
+	timed bool
+}
+
+var registry = make(map[string]definition)
+
+// Register makes an agent available under the passed name.
+//
+// Panics if an agent with the same name is already registered.
+func Register(name, description string, constructor Constructor) {
+	register(name, definition{description: description, constructor: constructor})
+}
+
+// RegisterTimed makes an agent that thinks for a fixed time per move
+// available under the passed name. Such an agent is expected to use all
+// CPU cores while it thinks, so it should not be run in parallel with
+// other agents when they are compared.
+//
+// Panics if an agent with the same name is already registered.
+func RegisterTimed(name, description string, constructor Constructor) {
+	register(name, definition{description: description, constructor: constructor, timed: true})
+}
+
+// RegisterCommand makes an agent that runs a command available under the
+// passed name. The command is given in the specification of the agent
+// before its parameters.
+//
+// Panics if an agent with the same name is already registered.
+func RegisterCommand(name, description string, constructor CommandConstructor) {
+	register(name, definition{description: description, commandConstructor: constructor})
+}
+
+func register(name string, def definition) {
+	if _, ok := registry[name]; ok {
+		panic("agent " + name + " is already registered")
+	}
+	registry[name] = def
+}
+
+// Names returns the names of all registered agents in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Usage returns a description of all registered agents and their
+// parameters suitable for a help message.
+func Usage() string {
+	var b strings.Builder
+	for _, name := range Names() {
+		fmt.Fprintf(&b, "  %s\n    \t%s\n", name, registry[name].description)
+	}
+	return b.String()
+}
+
+// IsTimed returns whether the agent of the specification was registered
+// with RegisterTimed. It is false for an unknown agent.
+func IsTimed(spec string) bool {
+	name := spec
+	if i := strings.IndexByte(spec, ':'); i >= 0 {
+		name = spec[:i]
+	}
+	return registry[name].timed
+}
+
+// New creates an agent from its specification.
+func New(spec string) (santase.Agent, error) {
+	name, rawParams := spec, ""
+	if i := strings.IndexByte(spec, ':'); i >= 0 {
+		name, rawParams = spec[:i], spec[i+1:]
+	}
+
+	def, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown agent %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+
+	command := ""
+	if def.commandConstructor != nil {
+		command, rawParams = rawParams, ""
+		if i := strings.IndexByte(command, ','); i >= 0 {
+			command, rawParams = command[:i], command[i+1:]
+		}
+		if strings.TrimSpace(command) == "" {
+			return nil, fmt.Errorf("agent %s: missing command, expected %s:command", name, name)
+		}
+	}
+
+	params, err := parseParams(name, rawParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var agent santase.Agent
+	if def.commandConstructor != nil {
+		agent, err = def.commandConstructor(command, params)
+	} else {
+		agent, err = def.constructor(params)
+	}
+	if err == nil {
+		err = params.err
+	}
+	if err == nil {
+		err = params.checkUnused()
+	}
+	if err != nil {
+		// the agent may have been created before its parameters were
+		// found to be invalid
+		Close(agent)
+		return nil, err
+	}
+
+	return agent, nil
+}
+
+func init() {
+	Register("random", "plays a random valid card", func(params *Params) (santase.Agent, error) {
+		return random.NewAgent(), nil
+	})
+
+	RegisterTimed("ismcts", "information set Monte Carlo tree search; parameters: "+
+		"c - exploration constant (default 5.4), budget - time per move (default 2s)",
+		func(params *Params) (santase.Agent, error) {
+			c := params.Float("c", 5.4)
+			budget := params.Duration("budget", 2*time.Second)
+			if budget <= 0 {
+				return nil, fmt.Errorf("agent ismcts: budget must be positive")
+			}
+			return ismcts.NewAgent(c, budget), nil
+		})
+
+	RegisterCommand("exec", "runs the command as a bot speaking the text protocol described in the README, "+
+		"for example exec:/path/to/bot; parameters: timeout - time to start and per move (default 10s)",
+		func(command string, params *Params) (santase.Agent, error) {
+			timeout := params.Duration("timeout", external.DefaultTimeout)
+			agent, err := external.NewAgent(command, timeout)
+			if err != nil {
+				return nil, fmt.Errorf("agent exec: %v", err)
+			}
+			return agent, nil
+		})
+}
+
+// Close releases what the agents hold, for example the processes of
+// external bots, if they implement io.Closer. Errors are logged.
+func Close(all ...santase.Agent) {
+	for _, agent := range all {
+		if c, ok := agent.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	santase "github.com/nvlbg/santase-ai"
+)
+
+// closingAgent counts how many times it was closed.
+type closingAgent struct {
+	closed int
+}
+
+func (*closingAgent) GetMove(game *santase.Game) santase.Move {
+	panic("closingAgent asked for a move")
+}
+
+func (a *closingAgent) Close() error {
+	a.closed++
+	return nil
+}
+
+// lastClosing is the agent last created by the closing agent, which takes
+// the parameter n.
+var lastClosing *closingAgent
+
+func init() {
+	Register("closing", "a test agent that counts how many times it is closed", func(params *Params) (santase.Agent, error) {
+		params.Int("n", 0)
+		lastClosing = &closingAgent{}
+		return lastClosing, nil
+	})
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		spec string
+
+		// err is a part of the error expected, or empty if the agent
+		// should be created
+		err string
+	}{
+		{"random", ""},
+		{"ismcts", ""},
+		{"ismcts:c=3,budget=500ms", ""},
+		{"closing:n=2", ""},
+		{"minimax", `unknown agent "minimax"`},
+		{"", `unknown agent ""`},
+		{"exec", "missing command"},
+		{"exec: ,timeout=1s", "missing command"},
+		{"ismcts:c", `invalid parameter "c", expected name=value`},
+		{"ismcts:=3", `invalid parameter "=3", expected name=value`},
+		{"ismcts:c=3,c=4", "parameter c is given more than once"},
+		{"ismcts:c=high", `invalid value "high" for parameter c, expected a number`},
+		{"ismcts:budget=2", `invalid value "2" for parameter budget, expected a duration`},
+		{"ismcts:budget=-1s", "budget must be positive"},
+		{"closing:n=two", `invalid value "two" for parameter n, expected an integer`},
+		{"random:c=3", "unknown parameter c, the agent takes no parameters"},
+		{"ismcts:depth=3,width=2", "unknown parameter depth, width (valid: budget, c)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			agent, err := New(test.spec)
+			switch {
+			case test.err == "" && err != nil:
+				t.Errorf("New(%q) = %v, want no error", test.spec, err)
+			case test.err == "" && agent == nil:
+				t.Errorf("New(%q) returned no agent", test.spec)
+			case test.err != "" && err == nil:
+				t.Errorf("New(%q) succeeded, want an error with %q", test.spec, test.err)
+			case test.err != "" && !strings.Contains(err.Error(), test.err):
+				t.Errorf("New(%q) = %v, want an error with %q", test.spec, err, test.err)
+			case test.err != "" && agent != nil:
+				t.Errorf("New(%q) returned an agent with the error", test.spec)
+			}
+		})
+	}
+}
+
+func TestNewClosesAgentWithInvalidParams(t *testing.T) {
+	for _, spec := range []string{"closing:n=two", "closing:m=2"} {
+		lastClosing = nil
+		if _, err := New(spec); err == nil {
+			t.Fatalf("New(%q) succeeded, want an error", spec)
+		}
+		if lastClosing == nil || lastClosing.closed != 1 {
+			t.Errorf("New(%q) did not close the agent it created", spec)
+		}
+	}
+}
+
+func TestParamsGetters(t *testing.T) {
+	params, err := parseParams("test", "s=text,i=-3,f=2.5,d=1m30s,empty=")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := params.String("s", "default"); got != "text" {
+		t.Errorf("String(s) = %q, want %q", got, "text")
+	}
+	if got := params.String("empty", "default"); got != "" {
+		t.Errorf("String(empty) = %q, want the empty value", got)
+	}
+	if got := params.String("missing", "default"); got != "default" {
+		t.Errorf("String(missing) = %q, want the default", got)
+	}
+	if got := params.Int("i", 7); got != -3 {
+		t.Errorf("Int(i) = %d, want -3", got)
+	}
+	if got := params.Int("missing", 7); got != 7 {
+		t.Errorf("Int(missing) = %d, want the default", got)
+	}
+	if got := params.Float("f", 1); got != 2.5 {
+		t.Errorf("Float(f) = %v, want 2.5", got)
+	}
+	if got := params.Float("missing", 1); got != 1 {
+		t.Errorf("Float(missing) = %v, want the default", got)
+	}
+	if got := params.Duration("d", time.Second); got != 90*time.Second {
+		t.Errorf("Duration(d) = %v, want 1m30s", got)
+	}
+	if got := params.Duration("missing", time.Second); got != time.Second {
+		t.Errorf("Duration(missing) = %v, want the default", got)
+	}
+
+	if params.err != nil {
+		t.Errorf("valid values were reported as %v", params.err)
+	}
+	if err := params.checkUnused(); err != nil {
+		t.Errorf("checkUnused() = %v after all parameters were read", err)
+	}
+}
+
+func TestParamsMalformedValues(t *testing.T) {
+	tests := []struct {
+		name string
+		get  func(params *Params)
+		want string
+	}{
+		{"int", func(params *Params) { params.Int("v", 0) }, "expected an integer"},
+		{"float", func(params *Params) { params.Float("v", 0) }, "expected a number"},
+		{"duration", func(params *Params) { params.Duration("v", 0) }, "expected a duration"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params, err := parseParams("test", "v=1x")
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.get(params)
+			if params.err == nil || !strings.Contains(params.err.Error(), test.want) {
+				t.Errorf("the malformed value was reported as %v, want an error with %q", params.err, test.want)
+			}
+		})
+	}
+}
+
+func TestIsTimed(t *testing.T) {
+	tests := []struct {
+		spec string
+		want bool
+	}{
+		{"ismcts", true},
+		{"ismcts:budget=500ms", true},
+		{"random", false},
+		{"exec:/path/to/bot,timeout=5s", false},
+		{"minimax", false},
+	}
+
+	for _, test := range tests {
+		if got := IsTimed(test.spec); got != test.want {
+			t.Errorf("IsTimed(%q) = %v, want %v", test.spec, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package external
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// deal is what the bot is told about the deal it plays in. The view of
+// the deal describes the position when the bot first plays in it and the
+// events of the moves what happened between the moves of the bot.
+type deal struct {
+	view *santase.Game
+
+	// started is whether the bot has been told about the deal
+	started bool
+
+	// lines tell the bot about the events since it was last asked for a
+	// move
+	lines []string
+
+	// lead is the last move of the opponent, or nil if they have not
+	// played in the deal yet
+	lead *engine.Move
+}
+
+func newDeal(view *santase.Game) *deal {
+	return &deal{view: view}
+}
+
+// start returns the commands that tell the bot about the deal when it
+// first plays in it.
+func (d *deal) start() []string {
+	v := d.view
+	trump := engine.FormatSuit(v.GetTrump())
+	if card := v.GetTrumpCard(); card != nil {
+		trump = engine.FormatCard(*card)
+	}
+	order := "first"
+	if v.GetCardPlayed() != nil {
+		order = "second"
+	}
+	hand := v.GetHand()
+	lines := []string{fmt.Sprintf("deal %s trump %s %s", formatCards(hand.ToSlice()), trump, order)}
+
+	seen := v.GetSeenCards()
+	if len(seen) > 0 {
+		lines = append(lines, "seen "+formatCards(seen.ToSlice()))
+		known := v.GetKnownOpponentCards()
+		if len(known) > 0 {
+			lines = append(lines, "known "+formatCards(known.ToSlice()))
+		}
+		lines = append(lines, fmt.Sprintf("score %d %d", v.GetScore(), v.GetOpponentScore()))
+		if v.IsClosed() {
+			lines = append(lines, "closed")
+		}
+	}
+
+	if card := v.GetCardPlayed(); card != nil {
+		// the view leaves out a marriage announced on the first lead
+		m := engine.Move{Card: *card}
+		m.IsAnnouncement = len(seen) == 0 && d.lead != nil && d.lead.Card == *card && d.lead.IsAnnouncement
+		lines = append(lines, "opponent "+engine.FormatMove(m))
+	}
+	return lines
+}
+
+// watch turns the events of a move into the commands that tell the bot
+// playing as the player about it: the moves of the opponent and the cards
+// the bot draws.
+func (d *deal) watch(p engine.Player, events []engine.Event) {
+	for _, e := range events {
+		switch e := e.(type) {
+		case engine.CardPlayed:
+			if e.Player == p {
+				break
+			}
+			m := e.Move
+			m.Claim = false
+			d.lead = &m
+			d.lines = append(d.lines, "opponent "+engine.FormatMove(m))
+		case engine.CardDrawn:
+			if e.Player == p {
+				d.lines = append(d.lines, "drawn "+engine.FormatCard(e.Card))
+			}
+		}
+	}
+}
+
+// update returns the commands that tell the bot what happened since it
+// was last asked for a move, or about the whole deal if it has not been
+// told about it yet.
+func (d *deal) update() []string {
+	lines := d.lines
+	if !d.started {
+		lines = d.start()
+		d.started = true
+	}
+	d.lines = nil
+	return lines
+}
+
+// formatCards returns the notation of the cards sorted by suit and rank
+// and separated by spaces.
+func formatCards(cards []santase.Card) string {
+	sort.Slice(cards, func(i, j int) bool {
+		return cards[i].Suit < cards[j].Suit || (cards[i].Suit == cards[j].Suit && cards[i].Rank < cards[j].Rank)
+	})
+	notation := make([]string, len(cards))
+	for i, card := range cards {
+		notation[i] = engine.FormatCard(card)
+	}
+	return strings.Join(notation, " ")
+}
+
+// checkMove returns an error if the view would not accept the move. The
+// view panics on illegal moves with messages that do not say which agent
+// chose them.
+func checkMove(view *santase.Game, m santase.Move) error {
+	hand := view.GetHand()
+	played := view.GetCardPlayed()
+	seen := len(view.GetSeenCards())
+	trump := view.GetTrump()
+	trumpCard := view.GetTrumpCard()
+
+	if (m.SwitchTrumpCard || m.CloseGame || m.IsAnnouncement) && played != nil {
+		return fmt.Errorf("only the player leading a trick can switch the trump card, close the game or announce a marriage")
+	}
+	if (m.SwitchTrumpCard || m.CloseGame || m.IsAnnouncement) && seen == 0 {
+		return fmt.Errorf("cannot switch the trump card, close the game or announce a marriage on the first lead")
+	}
+
+	if m.SwitchTrumpCard {
+		nine := santase.NewCard(santase.Nine, trump)
+		switch {
+		case trumpCard == nil || seen >= 10:
+			return fmt.Errorf("cannot switch the trump card with two cards or fewer left in the stack")
+		case view.IsClosed():
+			return fmt.Errorf("cannot switch the trump card after the game has been closed")
+		case !hand.HasCard(nine):
+			return fmt.Errorf("cannot switch the trump card without the nine of trumps")
+		}
+		hand.RemoveCard(nine)
+		hand.AddCard(*trumpCard)
+	}
+
+	if m.CloseGame && (view.IsClosed() || trumpCard == nil || seen >= 10) {
+		return fmt.Errorf("cannot close the game now")
+	}
+
+	if m.IsAnnouncement {
+		partner := santase.NewCard(santase.Queen, m.Card.Suit)
+		if m.Card.Rank == santase.Queen {
+			partner.Rank = santase.King
+		}
+		if (m.Card.Rank != santase.Queen && m.Card.Rank != santase.King) || !hand.HasCard(partner) {
+			return fmt.Errorf("cannot announce a marriage without both the queen and the king")
+		}
+	}
+
+	if !hand.HasCard(m.Card) {
+		return fmt.Errorf("card %s is not in the hand", engine.FormatCard(m.Card))
+	}
+	if played != nil && (view.IsClosed() || trumpCard == nil) {
+		responses := hand.GetValidResponses(*played, trump)
+		if !responses.HasCard(m.Card) {
+			return fmt.Errorf("card %s does not follow the suit or trump when required", engine.FormatCard(m.Card))
+		}
+	}
+	return nil
+}
+
+// anyMove returns a valid move of the view.
+func anyMove(view *santase.Game) santase.Move {
+	hand := view.GetHand()
+	if played := view.GetCardPlayed(); played != nil && (view.IsClosed() || view.GetTrumpCard() == nil) {
+		hand = hand.GetValidResponses(*played, view.GetTrump())
+	}
+	cards := hand.ToSlice()
+	return santase.Move{Card: cards[0]}
+}
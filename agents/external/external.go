@@ -0,0 +1,367 @@
+// Package external implements an agent that runs a bot in a separate
+// process and talks to it with a line based text protocol, in the spirit
+// of the UCI protocol of chess engines. The bot can be written in any
+// language; it reads commands from its standard input and writes its
+// answers to its standard output, one per line. Its standard error is
+// passed through, so it can be used for logging.
+//
+// Cards are written as a rank (9, J, Q, K, T or A) followed by a suit (C,
+// D, H or S), for example QS, and moves in the notation of the saved
+// deals: a card optionally preceded by ~ to switch the trump card first
+// and # to close the game, and followed by + to announce a marriage, for
+// example ~#QS+.
+//
+// The commands sent to the bot are:
+//
+//	santase 1
+//		sent once after the bot is started; the bot answers with a line
+//		"ready" when it can play
+//	deal <cards...> trump <card> first|second
+//		a new deal starts; the bot holds the listed cards, the card
+//		under the stack is the trump card and the bot leads first or
+//		second
+//	drawn <card>
+//		the bot drew the card from the stack
+//	opponent <move>
+//		the opponent played the move; a marriage may be announced even
+//		on the first lead, but then its points count only once the
+//		opponent takes a trick
+//	go
+//		the bot is on turn; it answers with a line "move <move>"
+//	quit
+//		the bot should exit
+//
+// When the bot joins a deal that is already in progress, for example one
+// continued from a save, the deal command lists the cards the bot holds
+// now and is followed by the lines
+//
+//	seen <cards...>
+//		the cards taken in the tricks played so far
+//	known <cards...>
+//		the cards the bot knows the opponent holds
+//	score <own> <opponent>
+//		the points collected so far
+//	closed
+//		only if the game has been closed
+//
+// with the lines that would list no cards left out. When all cards have
+// been drawn the trump card is given as the letter of the trump suit. If
+// the opponent leads the current trick, the deal is followed by an
+// opponent command with their card.
+//
+// The bot may write lines starting with "info" at any time; they are
+// ignored. A bot that does not answer in time, answers with an illegal
+// move or exits makes the agent panic, which ends the deal with an error
+// like any other illegal move. The bot is stopped then and only started
+// again if the agent is asked for another move.
+package external
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// DefaultTimeout is how long a bot is given to start or to choose a move
+// unless another timeout is passed to NewAgent.
+const DefaultTimeout = 10 * time.Second
+
+// protocolVersion is sent to the bot when it is started.
+const protocolVersion = 1
+
+// Agent is a santase agent whose moves are chosen by a bot running in
+// another process. The bot is started when the agent is first asked for a
+// move and it follows one deal at a time.
+type Agent struct {
+	command []string
+	timeout time.Duration
+
+	mu      sync.Mutex
+	process *process
+
+	// deal is what the bot has been told about the deal it follows. It
+	// has its own lock, so that the events of the deal are not held up
+	// while the bot chooses a move.
+	dealMu sync.Mutex
+	deal   *deal
+}
+
+// NewAgent creates an agent that runs the command, given as the program
+// followed by its arguments separated by spaces. The bot has the passed
+// time to start and to choose each of its moves.
+func NewAgent(command string, timeout time.Duration) (*Agent, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("missing command")
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("timeout must be positive")
+	}
+	return &Agent{command: fields, timeout: timeout}, nil
+}
+
+// GetMove implements santase.Agent.
+func (a *Agent) GetMove(game *santase.Game) santase.Move {
+	return a.GetMoveContext(context.Background(), game)
+}
+
+// GetMoveContext implements engine.ContextAgent. When the context is
+// cancelled the bot is stopped, since it would still answer the move it
+// was asked for, and any valid move is returned.
+func (a *Agent) GetMoveContext(ctx context.Context, game *santase.Game) santase.Move {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.process == nil {
+		p, err := a.start()
+		if err != nil {
+			a.fail("%v", err)
+		}
+		a.process = p
+	}
+
+	a.send(a.update(game)...)
+	a.send("go")
+
+	line, err := a.process.expect(ctx, "move", a.timeout)
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		a.stop()
+		return anyMove(game)
+	}
+	if err != nil {
+		a.fail("%v", err)
+	}
+	m, err := engine.ParseMove(line)
+	if err != nil {
+		a.fail("%v", err)
+	}
+	if m.Claim {
+		a.fail("invalid move %q: claims are made automatically", line)
+	}
+	move := m.AgentMove()
+	if err := checkMove(game, move); err != nil {
+		a.fail("illegal move %s: %v", line, err)
+	}
+	return move
+}
+
+// WatchEvents implements engine.Watcher. The bot is told about the events
+// when it is next asked for a move.
+func (a *Agent) WatchEvents(view *santase.Game, p engine.Player, events []engine.Event) {
+	a.dealMu.Lock()
+	defer a.dealMu.Unlock()
+
+	if a.deal == nil || a.deal.view != view {
+		a.deal = newDeal(view)
+	}
+	a.deal.watch(p, events)
+}
+
+// update returns the commands that tell the bot about the deal of the
+// view since it last moved. A deal other than the one the bot follows
+// replaces it.
+func (a *Agent) update(view *santase.Game) []string {
+	a.dealMu.Lock()
+	defer a.dealMu.Unlock()
+
+	if a.deal == nil || a.deal.view != view {
+		a.deal = newDeal(view)
+	}
+	return a.deal.update()
+}
+
+// restartDeal makes the bot be told about the whole deal again when it is
+// next asked for a move, since a new process knows nothing about it.
+func (a *Agent) restartDeal() {
+	a.dealMu.Lock()
+	defer a.dealMu.Unlock()
+
+	if a.deal != nil {
+		a.deal.started = false
+	}
+}
+
+// Close tells the bot to exit and waits for it for up to the timeout.
+func (a *Agent) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.process == nil {
+		return nil
+	}
+	p := a.process
+	a.process = nil
+	a.restartDeal()
+	return p.quit(a.timeout)
+}
+
+func (a *Agent) start() (*process, error) {
+	p, err := startProcess(a.command)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.send(fmt.Sprintf("santase %d", protocolVersion)); err != nil {
+		p.kill()
+		return nil, err
+	}
+	if _, err := p.expect(context.Background(), "ready", a.timeout); err != nil {
+		p.kill()
+		return nil, err
+	}
+	return p, nil
+}
+
+func (a *Agent) send(lines ...string) {
+	for _, line := range lines {
+		if err := a.process.send(line); err != nil {
+			a.fail("%v", err)
+		}
+	}
+}
+
+// stop kills the bot; it is started again when the agent is next asked
+// for a move.
+func (a *Agent) stop() {
+	if a.process != nil {
+		a.process.kill()
+	}
+	a.process = nil
+	a.restartDeal()
+}
+
+// fail stops the bot and panics, which santase.Game does as well when an
+// agent chooses an illegal move.
+func (a *Agent) fail(format string, args ...interface{}) {
+	a.stop()
+	panic(fmt.Sprintf("bot %s: %s", a.command[0], fmt.Sprintf(format, args...)))
+}
+
+// process is a running bot.
+type process struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	// lines receives the lines written by the bot until it is stopped
+	// and is closed when the bot closes its standard output
+	lines chan string
+
+	// done is closed when the bot is stopped; its further output is
+	// dropped
+	done chan struct{}
+	once sync.Once
+
+	// exited is closed when the bot has exited
+	exited chan struct{}
+}
+
+func startProcess(command []string) (*process, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &process{
+		cmd:    cmd,
+		stdin:  stdin,
+		lines:  make(chan string),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	go p.read(stdout)
+	return p, nil
+}
+
+func (p *process) read(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		select {
+		case p.lines <- strings.TrimSpace(scanner.Text()):
+		case <-p.done:
+		}
+	}
+	close(p.lines)
+	p.cmd.Wait()
+	close(p.exited)
+}
+
+func (p *process) send(line string) error {
+	if _, err := io.WriteString(p.stdin, line+"\n"); err != nil {
+		return fmt.Errorf("cannot send %q: %v", line, err)
+	}
+	return nil
+}
+
+// expect waits for a line starting with the keyword and returns the rest
+// of the line. Empty lines and lines starting with "info" are skipped.
+func (p *process) expect(ctx context.Context, keyword string, timeout time.Duration) (string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				return "", fmt.Errorf("exited while %s was expected", keyword)
+			}
+			command, rest := line, ""
+			if i := strings.IndexByte(line, ' '); i >= 0 {
+				command, rest = line[:i], strings.TrimSpace(line[i+1:])
+			}
+			switch command {
+			case "", "info":
+				continue
+			case keyword:
+				return rest, nil
+			}
+			return "", fmt.Errorf("unexpected line %q while %s was expected", line, keyword)
+		case <-timer.C:
+			return "", fmt.Errorf("did not answer with %s within %v", keyword, timeout)
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// quit asks the bot to exit and kills it if it does not exit in time.
+func (p *process) quit(timeout time.Duration) error {
+	p.send("quit")
+	p.stdin.Close()
+	p.stop()
+
+	select {
+	case <-p.exited:
+		return nil
+	case <-time.After(timeout):
+		p.kill()
+		return fmt.Errorf("did not exit within %v", timeout)
+	}
+}
+
+func (p *process) kill() {
+	p.stop()
+	p.stdin.Close()
+	p.cmd.Process.Kill()
+}
+
+func (p *process) stop() {
+	p.once.Do(func() { close(p.done) })
+}
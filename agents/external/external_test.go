@@ -0,0 +1,235 @@
+package external
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// TestHelperBot is not a real test; it is the bot run by the agents in the
+// other tests, which start the test binary again with the environment
+// variables below set. The bot writes the lines it reads to the file in
+// SANTASE_HELPER_LOG and answers each go as SANTASE_HELPER_BOT says:
+//
+//	script	with the next of the moves in SANTASE_HELPER_MOVES
+//	silent	not at all
+//	exit	by exiting
+func TestHelperBot(t *testing.T) {
+	mode := os.Getenv("SANTASE_HELPER_BOT")
+	if mode == "" {
+		return
+	}
+	defer os.Exit(0)
+
+	log, err := os.OpenFile(os.Getenv("SANTASE_HELPER_LOG"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer log.Close()
+
+	moves := strings.Fields(os.Getenv("SANTASE_HELPER_MOVES"))
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fmt.Fprintln(log, line)
+		switch line {
+		case "santase 1":
+			fmt.Println("info helper bot")
+			fmt.Println("ready")
+		case "go":
+			switch mode {
+			case "script":
+				fmt.Println("move " + moves[0])
+				moves = moves[1:]
+			case "exit":
+				os.Exit(3)
+			}
+		case "quit":
+			return
+		}
+	}
+}
+
+// newHelperBot returns an agent that runs TestHelperBot in the mode with
+// the moves and the name of the file the bot writes what it reads to.
+func newHelperBot(t *testing.T, mode string, timeout time.Duration, moves ...string) (*Agent, string) {
+	t.Helper()
+
+	log := filepath.Join(t.TempDir(), "bot.log")
+	t.Setenv("SANTASE_HELPER_BOT", mode)
+	t.Setenv("SANTASE_HELPER_LOG", log)
+	t.Setenv("SANTASE_HELPER_MOVES", strings.Join(moves, " "))
+
+	agent, err := NewAgent(os.Args[0]+" -test.run=^TestHelperBot$", timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { agent.Close() })
+	return agent, log
+}
+
+// readLog returns the lines the bot has read.
+func readLog(t *testing.T, log string) []string {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// firstLeadDeck is dealt so that PlayerOne holds the marriage in spades
+// and PlayerTwo the ace and ten of spades. The cards are drawn from the
+// end.
+const firstLeadDeck = "QSKS9CJC9DJD" + "AHTHKHQHASTS" + "JH" + "9HACTCKCQCADTDKDQD9SJS"
+
+// testDeal is a deal played by the user as PlayerOne against the agent.
+type testDeal struct {
+	t      *testing.T
+	state  engine.State
+	agents [2]santase.Agent
+	views  [2]*santase.Game
+}
+
+func newTestDeal(t *testing.T, agent santase.Agent) *testDeal {
+	deck, err := engine.ParseDeck(firstLeadDeck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := engine.NewState(deck, engine.PlayerOne, engine.Rules{})
+	return &testDeal{
+		t:      t,
+		state:  s,
+		agents: [2]santase.Agent{nil, agent},
+		views:  [2]*santase.Game{nil, engine.NewAgentView(s, engine.PlayerTwo, agent)},
+	}
+}
+
+// play plays the move of the user.
+func (d *testDeal) play(move string) {
+	d.t.Helper()
+
+	m, err := engine.ParseMove(move)
+	if err != nil {
+		d.t.Fatal(err)
+	}
+	d.apply(m)
+}
+
+// playAgent plays the move the agent chooses.
+func (d *testDeal) playAgent() {
+	d.t.Helper()
+	d.apply(engine.AgentMove(d.agents[engine.PlayerTwo], d.views[engine.PlayerTwo], d.state))
+}
+
+func (d *testDeal) apply(m engine.Move) {
+	d.t.Helper()
+
+	s, events, err := d.state.Apply(m)
+	if err != nil {
+		d.t.Fatalf("Apply(%s): %v", engine.FormatMove(m), err)
+	}
+	d.state = s
+	engine.UpdateAgents(d.agents, d.views, events)
+}
+
+// panicked returns the value f panics with, or nil.
+func panicked(f func()) (r interface{}) {
+	defer func() { r = recover() }()
+	f()
+	return nil
+}
+
+func TestAgentPlaysDeal(t *testing.T) {
+	agent, log := newHelperBot(t, "script", DefaultTimeout, "AS", "JS", "TS")
+	d := newTestDeal(t, agent)
+
+	// the bot learns about the marriage announced on the first lead,
+	// which its view leaves out
+	d.play("QS+")
+	d.playAgent()
+	d.playAgent()
+	d.play("KS")
+	d.play("9C")
+	d.playAgent()
+	if err := agent.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"santase 1",
+		"deal QH KH TH AH TS AS trump JH second",
+		"opponent QS+",
+		"go",
+		"drawn JS",
+		"go",
+		"opponent KS",
+		"drawn KD",
+		"opponent 9C",
+		"go",
+		"quit",
+	}
+	got := readLog(t, log)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("the bot read\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAgentTimeout(t *testing.T) {
+	agent, _ := newHelperBot(t, "silent", time.Second)
+	d := newTestDeal(t, agent)
+	d.play("9C")
+
+	r := panicked(d.playAgent)
+	if msg, _ := r.(string); !strings.Contains(msg, "did not answer with move within 1s") {
+		t.Errorf("the agent panicked with %v, want a timeout", r)
+	}
+}
+
+func TestAgentIllegalMove(t *testing.T) {
+	// the bot answers the nine of clubs with a card of the user
+	agent, _ := newHelperBot(t, "script", DefaultTimeout, "9D")
+	d := newTestDeal(t, agent)
+	d.play("9C")
+
+	r := panicked(d.playAgent)
+	if msg, _ := r.(string); !strings.Contains(msg, "illegal move 9D: card 9D is not in the hand") {
+		t.Errorf("the agent panicked with %v, want an illegal move", r)
+	}
+}
+
+func TestAgentBotExits(t *testing.T) {
+	agent, log := newHelperBot(t, "exit", DefaultTimeout)
+	d := newTestDeal(t, agent)
+	d.play("9C")
+
+	// the bot is started again and told about the deal when the agent is
+	// asked for a move after it exits
+	for i := 0; i < 2; i++ {
+		r := panicked(d.playAgent)
+		if msg, _ := r.(string); !strings.Contains(msg, "exited while move was expected") {
+			t.Errorf("the agent panicked with %v, want the bot to exit", r)
+		}
+	}
+
+	var deals int
+	for _, line := range readLog(t, log) {
+		if strings.HasPrefix(line, "deal ") {
+			deals++
+		}
+	}
+	if deals != 2 {
+		t.Errorf("the bot was told about the deal %d times, want 2", deals)
+	}
+}
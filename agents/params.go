@@ -0,0 +1,138 @@
+package agents
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Params holds the parameters given in the specification of an agent.
+//
+// The values are read with the typed getters which fall back to a default
+// value when a parameter is not given. An invalid value is reported as an
+// error by New, as is a parameter that the agent never asked for.
+type Params struct {
+	agent  string
+	values map[string]string
+	used   map[string]bool
+	err    error
+}
+
+func parseParams(agent, raw string) (*Params, error) {
+	params := &Params{
+		agent:  agent,
+		values: make(map[string]string),
+		used:   make(map[string]bool),
+	}
+
+	if raw == "" {
+		return params, nil
+	}
+
+	for _, pair := range strings.Split(raw, ",") {
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("agent %s: invalid parameter %q, expected name=value", agent, pair)
+		}
+		name, value := pair[:i], pair[i+1:]
+		if _, ok := params.values[name]; ok {
+			return nil, fmt.Errorf("agent %s: parameter %s is given more than once", agent, name)
+		}
+		params.values[name] = value
+	}
+
+	return params, nil
+}
+
+func (p *Params) lookup(name string) (string, bool) {
+	p.used[name] = true
+	value, ok := p.values[name]
+	return value, ok
+}
+
+func (p *Params) fail(name, value, expected string) {
+	if p.err == nil {
+		p.err = fmt.Errorf("agent %s: invalid value %q for parameter %s, expected %s", p.agent, value, name, expected)
+	}
+}
+
+// String returns the value of the parameter or def if it is not given.
+func (p *Params) String(name, def string) string {
+	if value, ok := p.lookup(name); ok {
+		return value
+	}
+	return def
+}
+
+// Int returns the value of the parameter as an integer or def if it is
+// not given.
+func (p *Params) Int(name string, def int) int {
+	value, ok := p.lookup(name)
+	if !ok {
+		return def
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		p.fail(name, value, "an integer")
+		return def
+	}
+	return result
+}
+
+// Float returns the value of the parameter as a floating point number or
+// def if it is not given.
+func (p *Params) Float(name string, def float64) float64 {
+	value, ok := p.lookup(name)
+	if !ok {
+		return def
+	}
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		p.fail(name, value, "a number")
+		return def
+	}
+	return result
+}
+
+// Duration returns the value of the parameter as a duration (for example
+// "500ms" or "2s") or def if it is not given.
+func (p *Params) Duration(name string, def time.Duration) time.Duration {
+	value, ok := p.lookup(name)
+	if !ok {
+		return def
+	}
+	result, err := time.ParseDuration(value)
+	if err != nil {
+		p.fail(name, value, "a duration such as 500ms or 2s")
+		return def
+	}
+	return result
+}
+
+func (p *Params) checkUnused() error {
+	var unknown []string
+	for name := range p.values {
+		if !p.used[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+
+	var known []string
+	for name := range p.used {
+		known = append(known, name)
+	}
+	sort.Strings(known)
+
+	if len(known) == 0 {
+		return fmt.Errorf("agent %s: unknown parameter %s, the agent takes no parameters",
+			p.agent, strings.Join(unknown, ", "))
+	}
+	return fmt.Errorf("agent %s: unknown parameter %s (valid: %s)",
+		p.agent, strings.Join(unknown, ", "), strings.Join(known, ", "))
+}
@@ -0,0 +1,203 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten"
+	santase "github.com/nvlbg/santase-ai"
+)
+
+// tweenFrames is the number of frames a card takes to move to a new place
+// at normal speed and dealFrames is the delay between the cards appearing
+// at the same time, for example when they are dealt.
+const (
+	tweenFrames = 18
+	dealFrames  = 4
+)
+
+// talonPose returns where new cards come from.
+func talonPose(l layout) pose {
+	return pose{x: float64(l.left(84)), y: float64(l.middle(360))}
+}
+
+// offscreenX returns the x coordinate to which the cards of a trick are
+// swept.
+func offscreenX(l layout) float64 {
+	return float64(l.width + l.size(80))
+}
+
+// pose is where and how a card is drawn.
+type pose struct {
+	x     float64
+	y     float64
+	angle float64
+
+	// face is 1 if the face of the card is shown and 0 if its back; the
+	// values in between are shown while the card is being flipped
+	face float64
+}
+
+func interpolate(from, to, t float64) float64 {
+	return from + (to-from)*t
+}
+
+// tween moves a card from one pose to another after waiting for a number
+// of frames.
+type tween struct {
+	from   pose
+	to     pose
+	delay  int
+	frame  int
+	frames int
+}
+
+func (t *tween) advance() {
+	if t.delay > 0 {
+		t.delay--
+	} else if t.frame < t.frames {
+		t.frame++
+	}
+}
+
+func (t *tween) isDone() bool {
+	return t.frame >= t.frames
+}
+
+// current returns the pose of the card in the current frame. The card
+// accelerates at the start and slows down at the end of the move.
+func (t *tween) current() pose {
+	if t.isDone() {
+		return t.to
+	}
+	p := float64(t.frame) / float64(t.frames)
+	p = p * p * (3 - 2*p)
+	return pose{
+		x:     interpolate(t.from.x, t.to.x, p),
+		y:     interpolate(t.from.y, t.to.y, p),
+		angle: interpolate(t.from.angle, t.to.angle, p),
+		face:  interpolate(t.from.face, t.to.face, p),
+	}
+}
+
+// animator keeps track of where each card was drawn and moves it smoothly
+// when the place it should be drawn at changes.
+type animator struct {
+	// speed multiplies the speed of the animations; 0 turns them off
+	speed  float64
+	tweens map[santase.Card]*tween
+	last   map[santase.Card]*card
+
+	// ghosts are cards that have left the table and are still moving
+	ghosts []ghost
+}
+
+// ghost is a card that is no longer on the table but is still moving.
+type ghost struct {
+	obj   *card
+	tween *tween
+}
+
+func newAnimator(speed float64) *animator {
+	return &animator{
+		speed:  speed,
+		tweens: make(map[santase.Card]*tween),
+		last:   make(map[santase.Card]*card),
+	}
+}
+
+// frames returns the number of frames an animation of base frames at
+// normal speed takes.
+func (a *animator) frames(base int) int {
+	if a.speed <= 0 {
+		return 0
+	}
+	return int(math.Round(float64(base) / a.speed))
+}
+
+func (a *animator) newTween(from, to pose, delay int) *tween {
+	return &tween{from: from, to: to, delay: delay, frames: a.frames(tweenFrames)}
+}
+
+// animate sets the pose in which each of the cards is drawn in this frame.
+// Cards that were not drawn before come from the talon, cards that are
+// no longer drawn are swept away to the passed y coordinate and cards the
+// user drags follow the cursor without delay.
+func (a *animator) animate(objects []*card, l layout, sweepY int) {
+	seen := make(map[santase.Card]bool)
+	delay := 0
+	for _, obj := range objects {
+		c := *obj.card
+		seen[c] = true
+		target := obj.target()
+
+		t, ok := a.tweens[c]
+		switch {
+		case obj.dragged:
+			t = &tween{from: target, to: target}
+			a.tweens[c] = t
+		case !ok:
+			t = a.newTween(talonPose(l), target, delay)
+			delay += a.frames(dealFrames)
+			a.tweens[c] = t
+		case t.to != target:
+			t = a.newTween(t.current(), target, 0)
+			a.tweens[c] = t
+		}
+
+		t.advance()
+		obj.pose = t.current()
+		a.last[c] = obj
+	}
+
+	for c, obj := range a.last {
+		if seen[c] {
+			continue
+		}
+		if a.speed > 0 {
+			from := a.tweens[c].current()
+			to := pose{x: offscreenX(l), y: float64(sweepY), angle: from.angle, face: from.face}
+			a.ghosts = append(a.ghosts, ghost{obj, a.newTween(from, to, 0)})
+		}
+		delete(a.tweens, c)
+		delete(a.last, c)
+	}
+
+	ghosts := a.ghosts[:0]
+	for _, g := range a.ghosts {
+		g.tween.advance()
+		g.obj.pose = g.tween.current()
+		if !g.tween.isDone() {
+			ghosts = append(ghosts, g)
+		}
+	}
+	a.ghosts = ghosts
+}
+
+// rename makes a card continue the move of another card, for example when
+// a card that stood for a hidden card turns out to be a different one.
+func (a *animator) rename(from, to santase.Card) {
+	if t, ok := a.tweens[from]; ok {
+		a.tweens[to] = t
+		delete(a.tweens, from)
+	}
+	if obj, ok := a.last[from]; ok {
+		a.last[to] = obj
+		delete(a.last, from)
+	}
+}
+
+// draw draws the cards that have left the table.
+func (a *animator) draw(screen *ebiten.Image) {
+	for _, g := range a.ghosts {
+		g.obj.draw(screen)
+	}
+}
+
+// isMoving returns whether the card is moving in this frame.
+func (a *animator) isMoving(obj *card) bool {
+	t, ok := a.tweens[*obj.card]
+	return ok && !t.isDone()
+}
@@ -0,0 +1,462 @@
+// Package api lets programs play deals against an agent over HTTP, so
+// that web front ends and scripts written in any language can use the
+// rules of the engine package. The user plays as engine.PlayerOne and the
+// agent answers their moves before each response is sent.
+//
+// The requests and responses are JSON objects:
+//
+//	POST /games {"deck": "...", "first": 2}
+//		starts a new deal and answers 201 Created with the game; both
+//		fields are optional: the deck is shuffled and the user plays
+//		first unless they are given
+//	GET /games/{id}
+//		answers with the game: {"id": "...", "view": {...}}, where the
+//		view is the deal as the user sees it (see engine.View)
+//	POST /games/{id}/moves {"move": "~QS+"}
+//		plays the move of the user, written like the moves of a saved
+//		deal, and the moves of the agent that follow it, and answers
+//		with the game and the events of the moves (see engine.Events);
+//		if any of them fails the game stays as it was
+//	GET /games/{id}/legal-moves
+//		answers {"moves": ["9C", "~#QS+", ...]} with the moves the user
+//		is allowed to make, or none if it is not their turn
+//
+// Errors are answered with a status other than 2xx and {"error": "..."};
+// a move sent while the previous one is still being played is answered
+// with 409 Conflict. A game that is not used for GameTimeout is removed.
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	mathrand "math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/agents"
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// GameTimeout is how long a game is kept after its last request.
+const GameTimeout = time.Hour
+
+// expireInterval is how often the games are checked for expiry.
+const expireInterval = time.Minute
+
+// maxBodySize limits the size of the request bodies.
+const maxBodySize = 1 << 16
+
+// AgentFactory creates the agent playing against the user in a new game.
+type AgentFactory func() (santase.Agent, error)
+
+// Server is an http.Handler which holds the games played through it.
+type Server struct {
+	rules    engine.Rules
+	newAgent AgentFactory
+
+	mu    sync.Mutex
+	rng   *mathrand.Rand
+	games map[string]*game
+
+	// stop ends the goroutine removing the expired games
+	stop chan struct{}
+}
+
+// game is a deal played against an agent.
+type game struct {
+	id    string
+	agent santase.Agent
+
+	// lastUsed and requests, the number of requests being handled, are
+	// guarded by the mutex of the server
+	lastUsed time.Time
+	requests int
+
+	mu     sync.Mutex
+	state  engine.State
+	record *engine.Record
+
+	// busy is whether a move is being played; the view is used only by
+	// the request playing it, so that the agent does not choose its moves
+	// with the game locked
+	busy bool
+	view *santase.Game
+}
+
+// gameResponse is the game as it is sent to the user.
+type gameResponse struct {
+	ID     string        `json:"id"`
+	View   engine.View   `json:"view"`
+	Events engine.Events `json:"events,omitempty"`
+}
+
+// NewServer creates a server for deals played with the rules against the
+// agents created by the factory. The decks are shuffled with the seed.
+func NewServer(rules engine.Rules, newAgent AgentFactory, seed int64) *Server {
+	s := &Server{
+		rules:    rules,
+		newAgent: newAgent,
+		rng:      mathrand.New(mathrand.NewSource(seed)),
+		games:    make(map[string]*game),
+		stop:     make(chan struct{}),
+	}
+	go s.expire()
+	return s
+}
+
+// Close removes all games and releases their agents.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+
+	for id, g := range s.games {
+		agents.Close(g.agent)
+		delete(s.games, id)
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "games":
+		if allow(w, r, http.MethodPost) {
+			s.create(w, r)
+		}
+	case len(parts) == 2 && parts[0] == "games":
+		if allow(w, r, http.MethodGet) {
+			s.withGame(w, parts[1], func(g *game) (interface{}, int, error) {
+				g.mu.Lock()
+				defer g.mu.Unlock()
+				return g.response(nil), http.StatusOK, nil
+			})
+		}
+	case len(parts) == 3 && parts[0] == "games" && parts[2] == "moves":
+		if allow(w, r, http.MethodPost) {
+			s.withGame(w, parts[1], func(g *game) (interface{}, int, error) {
+				return g.play(r)
+			})
+		}
+	case len(parts) == 3 && parts[0] == "games" && parts[2] == "legal-moves":
+		if allow(w, r, http.MethodGet) {
+			s.withGame(w, parts[1], func(g *game) (interface{}, int, error) {
+				return g.legalMoves(), http.StatusOK, nil
+			})
+		}
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s not found", r.URL.Path))
+	}
+}
+
+// create starts a new game.
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Deck  string `json:"deck"`
+		First int    `json:"first"`
+	}
+	if err := decode(r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	first := engine.PlayerOne
+	switch request.First {
+	case 0, 1:
+	case 2:
+		first = engine.PlayerTwo
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid first player %d, expected 1 or 2", request.First))
+		return
+	}
+
+	var deck []santase.Card
+	if request.Deck != "" {
+		var err error
+		if deck, err = engine.ParseDeck(request.Deck); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	} else {
+		s.mu.Lock()
+		deck = engine.NewDeck(s.rng)
+		s.mu.Unlock()
+	}
+
+	agent, err := s.newAgent()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	record := engine.NewRecord(deck, first, s.rules)
+	state := record.InitialState()
+	g := &game{
+		id:     newID(),
+		agent:  agent,
+		state:  state,
+		record: record,
+		view:   engine.NewAgentView(state, engine.PlayerTwo, agent),
+	}
+	state, moves, events, err := g.playAgent(state, nil)
+	if err != nil {
+		agents.Close(agent)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	g.commit(state, moves)
+
+	s.mu.Lock()
+	g.lastUsed = time.Now()
+	s.games[g.id] = g
+	s.mu.Unlock()
+
+	log.Printf("game %s: %v plays first", g.id, first)
+	writeJSON(w, http.StatusCreated, g.response(events))
+}
+
+// withGame calls handle with the game and sends the response it returns.
+// The game is not removed while handle runs.
+func (s *Server) withGame(w http.ResponseWriter, id string, handle func(g *game) (interface{}, int, error)) {
+	s.mu.Lock()
+	g, ok := s.games[id]
+	if ok {
+		g.requests++
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("game %s not found", id))
+		return
+	}
+	defer func() {
+		s.mu.Lock()
+		g.requests--
+		g.lastUsed = time.Now()
+		s.mu.Unlock()
+	}()
+
+	response, status, err := handle(g)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, status, response)
+}
+
+// expire removes the expired games every expireInterval until the server
+// is closed.
+func (s *Server) expire() {
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.removeExpired(time.Now())
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// removeExpired removes the games which have not been used for
+// GameTimeout before now. Games with requests being handled are kept.
+func (s *Server) removeExpired(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, g := range s.games {
+		if g.requests == 0 && now.Sub(g.lastUsed) > GameTimeout {
+			agents.Close(g.agent)
+			delete(s.games, id)
+			log.Printf("game %s expired", id)
+		}
+	}
+}
+
+// play plays the move of the user sent in the request and the moves of
+// the agent that follow it. The game changes only if all of them are
+// played; otherwise it stays as it was before the request.
+func (g *game) play(r *http.Request) (interface{}, int, error) {
+	var request struct {
+		Move string `json:"move"`
+	}
+	if err := decode(r, &request); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	m, err := engine.ParseMove(request.Move)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	g.mu.Lock()
+	switch {
+	case g.busy:
+		g.mu.Unlock()
+		return nil, http.StatusConflict, fmt.Errorf("the previous move is still being played")
+	case !g.state.IsOver() && g.state.ToMove() != engine.PlayerOne:
+		g.mu.Unlock()
+		return nil, http.StatusConflict, fmt.Errorf("it is not your turn")
+	}
+	state := g.state
+	g.busy = true
+	g.mu.Unlock()
+
+	state, moves, events, status, err := g.exchange(state, m)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.busy = false
+	if err != nil {
+		return nil, status, err
+	}
+	g.commit(state, moves)
+	return g.response(events), http.StatusOK, nil
+}
+
+// exchange plays the move of the user from the state and the moves of the
+// agent that follow it and returns the resulting state, the moves played
+// and their events. It is called with the game busy. If a move fails the
+// view of the agent is brought back to the state of the game.
+func (g *game) exchange(s engine.State, m engine.Move) (engine.State, []engine.Move, []engine.Event, int, error) {
+	s, events, err := s.Apply(m)
+	if err != nil {
+		return s, nil, nil, http.StatusConflict, err
+	}
+	engine.UpdateAgents([2]santase.Agent{nil, g.agent}, [2]*santase.Game{nil, g.view}, events)
+
+	s, moves, more, err := g.playAgent(s, []engine.Move{m})
+	if err != nil {
+		g.resetView()
+		return s, nil, nil, http.StatusInternalServerError, err
+	}
+	return s, moves, append(events, more...), http.StatusOK, nil
+}
+
+// playAgent plays the moves of the agent from the state until it is the
+// turn of the user or the deal is over. It returns the resulting state,
+// the moves played appended to the passed ones and their events.
+func (g *game) playAgent(s engine.State, moves []engine.Move) (_ engine.State, _ []engine.Move, events []engine.Event, err error) {
+	defer func() {
+		// santase.Game panics when an agent chooses an illegal move
+		if r := recover(); r != nil {
+			err = fmt.Errorf("agent: %v", r)
+		}
+	}()
+
+	for !s.IsOver() && s.ToMove() == engine.PlayerTwo {
+		m := engine.AgentMove(g.agent, g.view, s)
+		next, more, err := s.Apply(m)
+		if err != nil {
+			return s, moves, events, fmt.Errorf("agent: %w", err)
+		}
+		s = next
+		moves = append(moves, m)
+		engine.UpdateAgents([2]santase.Agent{nil, g.agent}, [2]*santase.Game{nil, g.view}, more)
+		events = append(events, more...)
+	}
+	return s, moves, events, nil
+}
+
+// commit makes the state after the moves the state of the game. It is
+// called with the game locked, or before the game is added to the server.
+func (g *game) commit(s engine.State, moves []engine.Move) {
+	g.state = s
+	for _, m := range moves {
+		g.record.Add(m)
+	}
+}
+
+// resetView replaces the view of the agent, which a failed exchange left
+// after moves that were not committed, with one of the state of the game.
+func (g *game) resetView() {
+	_, views, err := g.record.Replay([2]santase.Agent{nil, g.agent})
+	if err != nil {
+		// the moves of the record have all been played before
+		panic(err)
+	}
+	g.view = views[engine.PlayerTwo]
+}
+
+// legalMoves returns the moves the user is allowed to make.
+func (g *game) legalMoves() interface{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	moves := []string{}
+	if g.state.ToMove() == engine.PlayerOne {
+		for _, m := range g.state.LegalMoves() {
+			moves = append(moves, engine.FormatMove(m))
+		}
+	}
+	return struct {
+		Moves []string `json:"moves"`
+	}{moves}
+}
+
+// response returns the game as the user sees it after the events.
+func (g *game) response(events []engine.Event) gameResponse {
+	return gameResponse{
+		ID:     g.id,
+		View:   g.state.View(engine.PlayerOne),
+		Events: engine.ViewEvents(events, engine.PlayerOne),
+	}
+}
+
+// allow answers requests made with another method than the passed one
+// with an error and returns whether the request may be handled.
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed, expected %s", r.Method, method))
+	return false
+}
+
+// decode reads the JSON body of the request into v. An empty body leaves
+// v unchanged.
+func decode(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid request: %v", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("cannot send response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+// newID returns a random identifier of a game.
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	santase "github.com/nvlbg/santase-ai"
+	"github.com/nvlbg/santase-ai/agents/random"
+
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// testDeck deals the marriage in spades to the user, who plays first.
+const testDeck = "QSKS9CJC9DJDAHTHKHQHASTSJH9HACTCKCQCADTDKDQD9SJS"
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	_, ts := newTestServerWith(t, func() (santase.Agent, error) {
+		return random.NewAgent(), nil
+	})
+	return ts
+}
+
+// newTestServerWith returns a server of games against the agents created
+// by the factory and an HTTP server serving it.
+func newTestServerWith(t *testing.T, newAgent AgentFactory) (*Server, *httptest.Server) {
+	t.Helper()
+
+	server := NewServer(engine.Rules{}, newAgent, 1)
+	ts := httptest.NewServer(server)
+	t.Cleanup(func() {
+		ts.Close()
+		server.Close()
+	})
+	return server, ts
+}
+
+// do sends the request with the JSON body, if any, and decodes the
+// response into v. It returns the status of the response.
+func do(t *testing.T, method, url, body string, v interface{}) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s %s: Content-Type = %q, want application/json", method, url, ct)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	return resp.StatusCode
+}
+
+func createGame(t *testing.T, ts *httptest.Server, body string) gameResponse {
+	t.Helper()
+
+	var g gameResponse
+	if status := do(t, http.MethodPost, ts.URL+"/games", body, &g); status != http.StatusCreated {
+		t.Fatalf("POST /games %s = %d, want %d", body, status, http.StatusCreated)
+	}
+	if g.ID == "" {
+		t.Fatalf("POST /games %s returned a game without an id", body)
+	}
+	return g
+}
+
+func TestCreateGame(t *testing.T) {
+	ts := newTestServer(t)
+
+	g := createGame(t, ts, `{"deck": "`+testDeck+`"}`)
+	if got, want := engine.FormatDeck(g.View.Hand), "9CJC9DJDQSKS"; got != want {
+		t.Errorf("hand = %s, want %s", got, want)
+	}
+
+	var fetched gameResponse
+	if status := do(t, http.MethodGet, ts.URL+"/games/"+g.ID, "", &fetched); status != http.StatusOK {
+		t.Fatalf("GET /games/%s = %d, want %d", g.ID, status, http.StatusOK)
+	}
+	if fetched.ID != g.ID || len(fetched.View.Hand) != 6 {
+		t.Errorf("GET /games/%s = %+v, want the created game", g.ID, fetched)
+	}
+
+	// a shuffled deck and the agent playing first
+	g = createGame(t, ts, `{"first": 2}`)
+	if len(g.View.Hand) != 6 {
+		t.Errorf("hand = %v after the first move of the agent, want 6 cards", g.View.Hand)
+	}
+	if len(g.Events) == 0 {
+		t.Errorf("no events of the first move of the agent")
+	}
+}
+
+func TestPlayLegalMove(t *testing.T) {
+	ts := newTestServer(t)
+	g := createGame(t, ts, `{"deck": "`+testDeck+`"}`)
+
+	var legal struct {
+		Moves []string `json:"moves"`
+	}
+	if status := do(t, http.MethodGet, ts.URL+"/games/"+g.ID+"/legal-moves", "", &legal); status != http.StatusOK {
+		t.Fatalf("GET legal-moves = %d, want %d", status, http.StatusOK)
+	}
+	if len(legal.Moves) == 0 {
+		t.Fatal("no legal moves on the first lead")
+	}
+
+	var played gameResponse
+	status := do(t, http.MethodPost, ts.URL+"/games/"+g.ID+"/moves", `{"move": "`+legal.Moves[0]+`"}`, &played)
+	if status != http.StatusOK {
+		t.Fatalf("POST moves %s = %d, want %d", legal.Moves[0], status, http.StatusOK)
+	}
+	// the card of the user and the response of the agent
+	if len(played.View.Hand) != 6 || len(played.Events) < 3 {
+		t.Errorf("after the first trick the hand is %v and the events are %v", played.View.Hand, played.Events)
+	}
+}
+
+func TestRejectIllegalMove(t *testing.T) {
+	ts := newTestServer(t)
+	g := createGame(t, ts, `{"deck": "`+testDeck+`"}`)
+
+	for _, body := range []string{
+		`{"move": "AH"}`,  // a card of the agent
+		`{"move": "~9C"}`, // switching the trump card on the first lead
+		`{"move": "XX"}`,  // not a move
+		`{"move": ""}`,    // no move
+		`{"mvoe": "9C"}`,  // an unknown field
+	} {
+		var e struct {
+			Error string `json:"error"`
+		}
+		status := do(t, http.MethodPost, ts.URL+"/games/"+g.ID+"/moves", body, &e)
+		if status < 400 || status >= 500 || e.Error == "" {
+			t.Errorf("POST moves %s = %d %q, want a 4xx error", body, status, e.Error)
+		}
+	}
+
+	// the game is unchanged
+	var fetched gameResponse
+	do(t, http.MethodGet, ts.URL+"/games/"+g.ID, "", &fetched)
+	if len(fetched.View.Hand) != 6 || fetched.View.ToMove != engine.PlayerOne {
+		t.Errorf("the game changed after illegal moves: %+v", fetched)
+	}
+}
+
+func TestUnknownGame(t *testing.T) {
+	ts := newTestServer(t)
+
+	for _, path := range []string{"/games/0123456789abcdef", "/games/0123456789abcdef/legal-moves"} {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if status := do(t, http.MethodGet, ts.URL+path, "", &e); status != http.StatusNotFound || e.Error == "" {
+			t.Errorf("GET %s = %d %q, want %d", path, status, e.Error, http.StatusNotFound)
+		}
+	}
+
+	var e struct {
+		Error string `json:"error"`
+	}
+	if status := do(t, http.MethodPost, ts.URL+"/games/0123456789abcdef/moves", `{"move": "9C"}`, &e); status != http.StatusNotFound {
+		t.Errorf("POST moves of an unknown game = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+// failingAgent is a random agent that panics while failing is not zero.
+type failingAgent struct {
+	santase.Agent
+	failing *int32
+}
+
+func (a failingAgent) GetMove(game *santase.Game) santase.Move {
+	if atomic.LoadInt32(a.failing) != 0 {
+		panic("failing")
+	}
+	return a.Agent.GetMove(game)
+}
+
+func TestFailedAgentLeavesGameUnchanged(t *testing.T) {
+	failing := int32(1)
+	_, ts := newTestServerWith(t, func() (santase.Agent, error) {
+		return failingAgent{random.NewAgent(), &failing}, nil
+	})
+	g := createGame(t, ts, `{"deck": "`+testDeck+`"}`)
+
+	var e struct {
+		Error string `json:"error"`
+	}
+	url := ts.URL + "/games/" + g.ID
+	if status := do(t, http.MethodPost, url+"/moves", `{"move": "9C"}`, &e); status != http.StatusInternalServerError {
+		t.Fatalf("POST moves with a failing agent = %d %q, want %d", status, e.Error, http.StatusInternalServerError)
+	}
+	var fetched gameResponse
+	do(t, http.MethodGet, url, "", &fetched)
+	if engine.FormatDeck(fetched.View.Hand) != "9CJC9DJDQSKS" || fetched.View.ToMove != engine.PlayerOne {
+		t.Fatalf("the game changed after the agent failed: %+v", fetched)
+	}
+
+	// the agent follows the game again once it stops failing
+	atomic.StoreInt32(&failing, 0)
+	var played gameResponse
+	if status := do(t, http.MethodPost, url+"/moves", `{"move": "9C"}`, &played); status != http.StatusOK {
+		t.Fatalf("POST moves = %d, want %d", status, http.StatusOK)
+	}
+	if len(played.View.Hand) != 6 || len(played.Events) < 3 {
+		t.Errorf("after the first trick the hand is %v and the events are %v", played.View.Hand, played.Events)
+	}
+}
+
+func TestExpiredGamesRemoved(t *testing.T) {
+	server, ts := newTestServerWith(t, func() (santase.Agent, error) {
+		return random.NewAgent(), nil
+	})
+	g := createGame(t, ts, `{"deck": "`+testDeck+`"}`)
+	url := ts.URL + "/games/" + g.ID
+	later := time.Now().Add(2 * GameTimeout)
+
+	// a game with a request being handled is kept
+	server.mu.Lock()
+	server.games[g.ID].requests++
+	server.mu.Unlock()
+	server.removeExpired(later)
+	var fetched gameResponse
+	if status := do(t, http.MethodGet, url, "", &fetched); status != http.StatusOK {
+		t.Fatalf("GET %s of a game in use = %d, want %d", url, status, http.StatusOK)
+	}
+
+	server.mu.Lock()
+	server.games[g.ID].requests--
+	server.mu.Unlock()
+	server.removeExpired(later)
+	var e struct {
+		Error string `json:"error"`
+	}
+	if status := do(t, http.MethodGet, url, "", &e); status != http.StatusNotFound {
+		t.Errorf("GET %s of an expired game = %d, want %d", url, status, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,63 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/ebitenutil"
+	"github.com/hajimehoshi/ebiten/inpututil"
+	"github.com/hajimehoshi/ebiten/text"
+)
+
+// Colors of the buttons.
+var (
+	buttonColor         = color.NRGBA{0x00, 0x66, 0x00, 0xff}
+	buttonActiveColor   = color.NRGBA{0x00, 0x33, 0x00, 0xff}
+	buttonDisabledColor = color.NRGBA{0x00, 0x88, 0x00, 0xff}
+	disabledTextColor   = color.NRGBA{0xff, 0xff, 0xff, 0x60}
+)
+
+// button is a labelled area of the screen that does something when it is
+// clicked or when its key is pressed.
+type button struct {
+	label string
+	rect  image.Rectangle
+	key   ebiten.Key
+
+	// enabled is whether the button can be used now; a disabled button is
+	// dimmed and ignores the mouse and its key
+	enabled bool
+
+	// active is whether the option the button toggles is on
+	active bool
+}
+
+// pressed returns whether the button is enabled and was clicked or its key
+// was pressed in this frame.
+func (b *button) pressed(p *pointer) bool {
+	if !b.enabled {
+		return false
+	}
+	return inpututil.IsKeyJustPressed(b.key) || (p.Clicked() && image.Pt(p.X, p.Y).In(b.rect))
+}
+
+// draw draws the button with its label centered in it.
+func (b *button) draw(screen *ebiten.Image, r *resources) {
+	fill, textColor := buttonColor, color.Color(color.White)
+	switch {
+	case !b.enabled:
+		fill, textColor = buttonDisabledColor, disabledTextColor
+	case b.active:
+		fill = buttonActiveColor
+	}
+
+	l := r.layout
+	ebitenutil.DrawRect(screen, float64(b.rect.Min.X), float64(b.rect.Min.Y),
+		float64(b.rect.Dx()), float64(b.rect.Dy()), fill)
+	x := b.rect.Min.X + (b.rect.Dx()-len(b.label)*l.size(fontSizeSmall))/2
+	text.Draw(screen, b.label, r.fontFaceSmall, x, b.rect.Min.Y+l.size(28), textColor)
+}
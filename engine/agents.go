@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	santase "github.com/nvlbg/santase-ai"
+)
+
+// UpdateAgents keeps the agents' views of the deal in sync with the
+// events returned by Apply. The agents and their views are indexed by
+// player and a nil view (for example a human player) is skipped. Agents
+// that implement Watcher are passed the events once their view has been
+// updated.
+//
+// The view of the player that made the move is expected to have chosen
+// it with GetMove, so only the other player is told about it.
+//
+// santase.Game refuses marriages announced on the first lead, so the views
+// are told about such a move without the announcement and their scores
+// never include its points, even after the announcing player takes a
+// trick and the state counts them.
+func UpdateAgents(agents [2]santase.Agent, views [2]*santase.Game, events []Event) {
+	for _, e := range events {
+		switch e := e.(type) {
+		case CardPlayed:
+			if view := views[e.Player.Other()]; view != nil {
+				view.UpdateOpponentMove(agentMove(e.Move, events))
+			}
+		case CardDrawn:
+			if view := views[e.Player]; view != nil {
+				view.UpdateDrawnCard(e.Card)
+			}
+		}
+	}
+
+	for p, view := range views {
+		if w, ok := agents[p].(Watcher); ok && view != nil {
+			w.WatchEvents(view, Player(p), events)
+		}
+	}
+}
+
+// agentMove converts a move to the form santase agents understand. They do
+// not allow marriages to be announced on the first lead, so such an
+// announcement, which is pending in the passed events of the move, is left
+// out.
+func agentMove(m Move, events []Event) santase.Move {
+	move := m.AgentMove()
+	for _, e := range events {
+		if e, ok := e.(Announced); ok && e.Pending {
+			move.IsAnnouncement = false
+		}
+	}
+	return move
+}
+
+// Claimer can be implemented by agents that decide on their own when to
+// claim that they have collected 66 points. ShouldClaim is called with
+// the agent's view of the deal when it is about to lead and after it
+// chooses to announce a marriage.
+//
+// Agents that do not implement Claimer claim as soon as they have
+// collected 66 points.
+type Claimer interface {
+	ShouldClaim(game *santase.Game) bool
+}
+
+// Watcher can be implemented by agents that follow the deal by its events
+// and not only by their view, for example to learn about marriages
+// announced on the first lead, which the view leaves out. WatchEvents is
+// called by UpdateAgents with the view of the agent, the player it plays
+// as and the events of every move, including its own.
+type Watcher interface {
+	WatchEvents(view *santase.Game, p Player, events []Event)
+}
+
+// ContextAgent can be implemented by agents that can stop choosing their
+// move when the context is cancelled, for example because the user has
+// started a new game. The move returned after the context is cancelled is
+// ignored.
+type ContextAgent interface {
+	GetMoveContext(ctx context.Context, game *santase.Game) santase.Move
+}
+
+// contextAgent passes the context to a ContextAgent when the view asks it
+// for its move.
+type contextAgent struct {
+	ctx   context.Context
+	agent ContextAgent
+}
+
+func (a contextAgent) GetMove(game *santase.Game) santase.Move {
+	return a.agent.GetMoveContext(a.ctx, game)
+}
+
+// AgentMove asks the agent for the move of the player on turn. The view
+// is the agent's view of the deal. Unless the deal is played with
+// AutoClaim, the agent is also given the chance to claim 66.
+func AgentMove(agent santase.Agent, view *santase.Game, s State) Move {
+	return AgentMoveContext(context.Background(), agent, view, s)
+}
+
+// AgentMoveContext is like AgentMove but it passes the context to agents
+// that implement ContextAgent. Other agents are not interrupted when the
+// context is cancelled.
+func AgentMoveContext(ctx context.Context, agent santase.Agent, view *santase.Game, s State) Move {
+	p := s.ToMove()
+	claims := func(score int) bool {
+		if s.rules.AutoClaim {
+			return false
+		}
+		if claimer, ok := agent.(Claimer); ok {
+			return claimer.ShouldClaim(view)
+		}
+		return score >= 66
+	}
+
+	if s.CanClaim() && claims(s.Score(p)) {
+		return Move{Claim: true}
+	}
+
+	if a, ok := agent.(ContextAgent); ok {
+		view.SetAgent(contextAgent{ctx, a})
+		defer view.SetAgent(agent)
+	}
+
+	// the points of a marriage announced before taking a trick do not
+	// allow the player to claim until they take one
+	m := FromAgentMove(view.GetMove())
+	if m.IsAnnouncement && s.Tricks(p) > 0 && claims(s.Score(p)+marriagePoints(m.Card, s.trump)) {
+		m.Claim = true
+	}
+	return m
+}
+
+// NewAgentView creates the view of the deal for the agent playing as the
+// passed player. The state must be the initial state of the deal.
+func NewAgentView(s State, p Player, agent santase.Agent) *santase.Game {
+	view := santase.CreateGame(s.Hand(p), *s.TrumpCard(), s.ToMove() != p)
+	view.SetAgent(agent)
+	return &view
+}
+
+// PlayOut plays the deal from its initial state to the end with the moves
+// chosen by the agents, indexed by player. It returns the final state of
+// the deal or an error if one of the agents chooses an illegal move.
+func PlayOut(s State, agents [2]santase.Agent) (result State, err error) {
+	views := [2]*santase.Game{
+		NewAgentView(s, PlayerOne, agents[PlayerOne]),
+		NewAgentView(s, PlayerTwo, agents[PlayerTwo]),
+	}
+
+	defer func() {
+		// santase.Game panics when an agent chooses an illegal move
+		if r := recover(); r != nil {
+			result, err = s, fmt.Errorf("%v: %v", s.ToMove(), r)
+		}
+	}()
+
+	for !s.IsOver() {
+		p := s.ToMove()
+		next, events, err := s.Apply(AgentMove(agents[p], views[p], s))
+		if err != nil {
+			return s, fmt.Errorf("%v: %w", p, err)
+		}
+		s = next
+		UpdateAgents(agents, views, events)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	santase "github.com/nvlbg/santase-ai"
+)
+
+// firstLeadDeck is dealt so that PlayerOne holds the marriage in spades
+// and PlayerTwo holds the high trumps. The cards are drawn from the end.
+const firstLeadDeck = "QS KS 9C JC 9D JD  AH TH KH QH AS TS  JH  9H AC TC KC QC AD TD KD QD 9S JS"
+
+// newTestRecord returns a record of the deal started by PlayerOne with the
+// deck and the moves, both written in the notation of saved deals.
+func newTestRecord(t *testing.T, deck, moves string) *Record {
+	t.Helper()
+
+	cards, err := ParseDeck(strings.Join(strings.Fields(deck), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := NewRecord(cards, PlayerOne, Rules{})
+	for _, s := range strings.Fields(moves) {
+		m, err := ParseMove(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		record.Add(m)
+	}
+	return record
+}
+
+// idleAgent is never asked for a move because the moves of its player
+// are replayed from a record.
+type idleAgent struct{}
+
+func (idleAgent) GetMove(game *santase.Game) santase.Move {
+	panic("idleAgent asked for a move")
+}
+
+func TestAgentViewsLeaveOutFirstLeadMarriage(t *testing.T) {
+	// PlayerOne announces the marriage in spades on the first lead, loses
+	// the first trick and takes the second one with the king
+	record := newTestRecord(t, firstLeadDeck, "QS+ AS JS KS")
+
+	for _, p := range []Player{PlayerOne, PlayerTwo} {
+		var agents [2]santase.Agent
+		agents[p] = idleAgent{}
+		s, views, err := record.Replay(agents)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the views see the cards taken but not the marriage
+		scores := [2]int{s.Score(PlayerOne) - 20, s.Score(PlayerTwo)}
+		view := views[p]
+		if got, want := view.GetScore(), scores[p]; got != want {
+			t.Errorf("the view of %v has the score %d, want %d", p, got, want)
+		}
+		if got, want := view.GetOpponentScore(), scores[p.Other()]; got != want {
+			t.Errorf("the view of %v has the opponent score %d, want %d", p, got, want)
+		}
+	}
+}
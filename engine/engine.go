@@ -0,0 +1,91 @@
+// Package engine implements the rules of santase independently of any
+// user interface.
+//
+// A deal is represented by a State which is never modified in place.
+// Moves are applied to a state with Apply, which returns the resulting
+// state together with the events that happened as a consequence of the
+// move. This makes it possible to drive the exact same rules from the
+// GUI, from tests, simulators or servers.
+package engine
+
+import (
+	"math/rand"
+
+	santase "github.com/nvlbg/santase-ai"
+)
+
+// Player identifies one of the two players in a deal.
+type Player int
+
+// PlayerOne is the player at the bottom of the table (the user in the GUI)
+// and PlayerTwo is the player at the top of the table (the opponent).
+const (
+	PlayerOne Player = iota
+	PlayerTwo
+)
+
+// Other returns the opponent of the player.
+func (p Player) Other() Player {
+	return 1 - p
+}
+
+func (p Player) String() string {
+	switch p {
+	case PlayerOne:
+		return "player one"
+	case PlayerTwo:
+		return "player two"
+	}
+	return "invalid"
+}
+
+// Move is the action a player chooses on their turn. Besides what the
+// moves chosen by santase agents contain, a move can claim that the
+// player has collected 66 points. When Claim is set the card is not
+// played - the trump card switch, the closing of the game and the
+// announcement (if any) are made and then the deal ends.
+type Move struct {
+	Card            santase.Card
+	IsAnnouncement  bool
+	SwitchTrumpCard bool
+	CloseGame       bool
+	Claim           bool
+}
+
+// FromAgentMove converts a move chosen by a santase agent.
+func FromAgentMove(m santase.Move) Move {
+	return Move{
+		Card:            m.Card,
+		IsAnnouncement:  m.IsAnnouncement,
+		SwitchTrumpCard: m.SwitchTrumpCard,
+		CloseGame:       m.CloseGame,
+	}
+}
+
+// AgentMove converts the move to the form santase agents understand.
+// The claim, if any, is dropped.
+func (m Move) AgentMove() santase.Move {
+	return santase.Move{
+		Card:            m.Card,
+		IsAnnouncement:  m.IsAnnouncement,
+		SwitchTrumpCard: m.SwitchTrumpCard,
+		CloseGame:       m.CloseGame,
+	}
+}
+
+// Rules holds the optional rules a deal can be played with.
+type Rules struct {
+	// AutoClaim ends the deal as soon as a player collects 66 points
+	// instead of waiting for them to claim it.
+	AutoClaim bool
+}
+
+// NewDeck returns all cards in the game shuffled with rng.
+func NewDeck(rng *rand.Rand) []santase.Card {
+	deck := make([]santase.Card, len(santase.AllCards))
+	copy(deck, santase.AllCards)
+	rng.Shuffle(len(deck), func(i, j int) {
+		deck[i], deck[j] = deck[j], deck[i]
+	})
+	return deck
+}
@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	santase "github.com/nvlbg/santase-ai"
+)
+
+// Event describes something that happened in the deal as a consequence
+// of applying a move. Apply returns the events in the order they happened.
+type Event interface {
+	event()
+}
+
+// TrumpSwitched is emitted when a player exchanges the nine of trump for
+// the trump card.
+type TrumpSwitched struct {
+	Player    Player
+	TrumpCard santase.Card
+}
+
+// GameClosed is emitted when a player closes the game.
+type GameClosed struct {
+	Player Player
+}
+
+// Announced is emitted when a player announces a marriage. Pending tells
+// whether the player has not taken a trick yet, in which case the points
+// are added to their score only when they take one.
+type Announced struct {
+	Player  Player
+	Suit    santase.Suit
+	Points  int
+	Pending bool
+}
+
+// CardPlayed is emitted when a player places a card on the table. The
+// whole move is included so it can be passed on to the other player.
+type CardPlayed struct {
+	Player Player
+	Move   Move
+}
+
+// TrickWon is emitted when a trick is completed. Cards holds the led card
+// and the response in that order. Marriages holds the points of the
+// marriages the player announced before taking a trick, which count from
+// this trick on.
+type TrickWon struct {
+	Player    Player
+	Cards     [2]santase.Card
+	Points    int
+	Marriages int
+}
+
+// LastTrickBonusWon is emitted after TrickWon when the player wins the
+// last trick of a deal that has not been closed.
+type LastTrickBonusWon struct {
+	Player Player
+	Points int
+}
+
+// CardDrawn is emitted when a player draws a card from the stack.
+type CardDrawn struct {
+	Player Player
+	Card   santase.Card
+}
+
+// Claimed is emitted when a player claims to have collected 66 points.
+// Valid tells whether the claim is true.
+type Claimed struct {
+	Player Player
+	Valid  bool
+}
+
+// GameOver is emitted when the deal ends.
+type GameOver struct {
+	Winner Player
+}
+
+func (TrumpSwitched) event()     {}
+func (GameClosed) event()        {}
+func (Announced) event()         {}
+func (CardPlayed) event()        {}
+func (TrickWon) event()          {}
+func (LastTrickBonusWon) event() {}
+func (CardDrawn) event()         {}
+func (Claimed) event()           {}
+func (GameOver) event()          {}
+
+// Events is a list of events that can be encoded in JSON, for example to
+// send them over a network. Each event is an object with a "type" field
+// holding the name of its type with a lower case first letter, like
+// "cardPlayed", and its fields encoded like those of a record.
+type Events []Event
+
+type jsonEvent struct {
+	Type      string   `json:"type"`
+	Player    int      `json:"player,omitempty"`
+	Card      string   `json:"card,omitempty"`
+	Move      string   `json:"move,omitempty"`
+	Cards     []string `json:"cards,omitempty"`
+	Suit      string   `json:"suit,omitempty"`
+	Points    int      `json:"points,omitempty"`
+	Pending   bool     `json:"pending,omitempty"`
+	Marriages int      `json:"marriages,omitempty"`
+	Valid     bool     `json:"valid,omitempty"`
+	Winner    int      `json:"winner,omitempty"`
+}
+
+// MarshalJSON encodes the events as an array of objects.
+func (es Events) MarshalJSON() ([]byte, error) {
+	out := make([]jsonEvent, len(es))
+	for i, e := range es {
+		switch e := e.(type) {
+		case TrumpSwitched:
+			out[i] = jsonEvent{Type: "trumpSwitched", Player: int(e.Player) + 1, Card: FormatCard(e.TrumpCard)}
+		case GameClosed:
+			out[i] = jsonEvent{Type: "gameClosed", Player: int(e.Player) + 1}
+		case Announced:
+			out[i] = jsonEvent{Type: "announced", Player: int(e.Player) + 1, Suit: FormatSuit(e.Suit), Points: e.Points, Pending: e.Pending}
+		case CardPlayed:
+			out[i] = jsonEvent{Type: "cardPlayed", Player: int(e.Player) + 1, Move: FormatMove(e.Move)}
+		case TrickWon:
+			cards := []string{FormatCard(e.Cards[0]), FormatCard(e.Cards[1])}
+			out[i] = jsonEvent{Type: "trickWon", Player: int(e.Player) + 1, Cards: cards, Points: e.Points, Marriages: e.Marriages}
+		case LastTrickBonusWon:
+			out[i] = jsonEvent{Type: "lastTrickBonusWon", Player: int(e.Player) + 1, Points: e.Points}
+		case CardDrawn:
+			out[i] = jsonEvent{Type: "cardDrawn", Player: int(e.Player) + 1, Card: FormatCard(e.Card)}
+		case Claimed:
+			out[i] = jsonEvent{Type: "claimed", Player: int(e.Player) + 1, Valid: e.Valid}
+		case GameOver:
+			out[i] = jsonEvent{Type: "gameOver", Winner: int(e.Winner) + 1}
+		default:
+			return nil, fmt.Errorf("unknown event %T", e)
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes events encoded with MarshalJSON.
+func (es *Events) UnmarshalJSON(data []byte) error {
+	var in []jsonEvent
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	events := make(Events, 0, len(in))
+	for _, e := range in {
+		player := Player(e.Player - 1)
+		if e.Type == "gameOver" {
+			player = Player(e.Winner - 1)
+		}
+		if player != PlayerOne && player != PlayerTwo {
+			return fmt.Errorf("invalid player in %s event, expected 1 or 2", e.Type)
+		}
+
+		var event Event
+		var err error
+		switch e.Type {
+		case "trumpSwitched":
+			var card santase.Card
+			card, err = ParseCard(e.Card)
+			event = TrumpSwitched{Player: player, TrumpCard: card}
+		case "gameClosed":
+			event = GameClosed{Player: player}
+		case "announced":
+			var suit santase.Suit
+			suit, err = ParseSuit(e.Suit)
+			event = Announced{Player: player, Suit: suit, Points: e.Points, Pending: e.Pending}
+		case "cardPlayed":
+			var m Move
+			m, err = ParseMove(e.Move)
+			event = CardPlayed{Player: player, Move: m}
+		case "trickWon":
+			var cards []santase.Card
+			cards, err = ParseCards(strings.Join(e.Cards, ""))
+			if err == nil && len(cards) != 2 {
+				err = fmt.Errorf("trick must have 2 cards")
+			}
+			if err == nil {
+				event = TrickWon{Player: player, Cards: [2]santase.Card{cards[0], cards[1]}, Points: e.Points, Marriages: e.Marriages}
+			}
+		case "lastTrickBonusWon":
+			event = LastTrickBonusWon{Player: player, Points: e.Points}
+		case "cardDrawn":
+			var card santase.Card
+			card, err = ParseCard(e.Card)
+			event = CardDrawn{Player: player, Card: card}
+		case "claimed":
+			event = Claimed{Player: player, Valid: e.Valid}
+		case "gameOver":
+			event = GameOver{Winner: player}
+		default:
+			err = fmt.Errorf("unknown event type %q", e.Type)
+		}
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+	*es = events
+	return nil
+}
@@ -0,0 +1,103 @@
+package engine
+
+import santase "github.com/nvlbg/santase-ai"
+
+// GamePointsToWin is the number of game points a player needs to collect
+// to win a match.
+const GamePointsToWin = 11
+
+// DealResult holds the outcome of a deal that has been played in a match.
+type DealResult struct {
+	Winner     Player
+	GamePoints int
+	Scores     [2]int
+}
+
+// Result returns the outcome of the deal.
+//
+// Panics if the deal is not over yet.
+func (s State) Result() DealResult {
+	return DealResult{
+		Winner:     s.Winner(),
+		GamePoints: s.GamePoints(),
+		Scores:     [2]int{s.Score(PlayerOne), s.Score(PlayerTwo)},
+	}
+}
+
+// Match is a sequence of deals played until one of the players collects
+// GamePointsToWin game points. The players take turns to play first in
+// the deals.
+type Match struct {
+	gamePoints [2]int
+	deals      []DealResult
+	first      Player
+	rules      Rules
+}
+
+// NewMatch creates a new match in which the passed player plays first
+// in the first deal. All deals are played with the passed optional rules.
+func NewMatch(first Player, rules Rules) Match {
+	return Match{first: first, rules: rules}
+}
+
+// NewDeal deals the passed deck for the next deal of the match.
+//
+// Panics if the match is over.
+func (m *Match) NewDeal(deck []santase.Card) State {
+	if m.IsOver() {
+		panic("the match is over")
+	}
+	return NewState(deck, m.first, m.rules)
+}
+
+// AddDeal records the result of a finished deal and awards the game
+// points to its winner.
+//
+// Panics if the deal is not over or the match is over.
+func (m *Match) AddDeal(s State) DealResult {
+	if m.IsOver() {
+		panic("the match is over")
+	}
+
+	result := s.Result()
+	m.gamePoints[result.Winner] += result.GamePoints
+	m.deals = append(m.deals, result)
+	m.first = m.first.Other()
+	return result
+}
+
+// Deals returns the results of the deals played so far.
+func (m *Match) Deals() []DealResult {
+	deals := make([]DealResult, len(m.deals))
+	copy(deals, m.deals)
+	return deals
+}
+
+// GamePoints returns the game points the player has collected so far.
+func (m *Match) GamePoints(p Player) int {
+	return m.gamePoints[p]
+}
+
+// First returns the player that plays first in the next deal.
+func (m *Match) First() Player {
+	return m.first
+}
+
+// IsOver returns whether one of the players has collected enough game
+// points to win the match.
+func (m *Match) IsOver() bool {
+	return m.gamePoints[PlayerOne] >= GamePointsToWin || m.gamePoints[PlayerTwo] >= GamePointsToWin
+}
+
+// Winner returns the player that won the match.
+//
+// Panics if the match is not over yet.
+func (m *Match) Winner() Player {
+	if !m.IsOver() {
+		panic("the match is not over")
+	}
+	if m.gamePoints[PlayerOne] > m.gamePoints[PlayerTwo] {
+		return PlayerOne
+	}
+	return PlayerTwo
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// finishedDeal returns the state of a deal won by the player in which the
+// loser took the tricks and collected the points.
+func finishedDeal(winner Player, loserTricks, loserScore int) State {
+	s := State{isOver: true, winner: winner}
+	s.scores[winner] = 66
+	s.tricks[winner] = 6 - loserTricks
+	s.scores[winner.Other()] = loserScore
+	s.tricks[winner.Other()] = loserTricks
+	return s
+}
+
+func TestMatchAddsGamePointsUntilEleven(t *testing.T) {
+	deals := []struct {
+		deal       State
+		gamePoints [2]int
+	}{
+		{finishedDeal(PlayerOne, 0, 0), [2]int{3, 0}},
+		{finishedDeal(PlayerTwo, 2, 40), [2]int{3, 1}},
+		{finishedDeal(PlayerOne, 1, 20), [2]int{5, 1}},
+		{finishedDeal(PlayerTwo, 0, 0), [2]int{5, 4}},
+		{finishedDeal(PlayerOne, 3, 50), [2]int{6, 4}},
+		{finishedDeal(PlayerOne, 2, 32), [2]int{8, 4}},
+		{finishedDeal(PlayerTwo, 3, 33), [2]int{8, 5}},
+		{finishedDeal(PlayerOne, 0, 0), [2]int{11, 5}},
+	}
+
+	m := NewMatch(PlayerTwo, Rules{})
+	first := PlayerTwo
+	for i, d := range deals {
+		if m.IsOver() {
+			t.Fatalf("deal %d: the match is over at %d:%d", i+1, m.GamePoints(PlayerOne), m.GamePoints(PlayerTwo))
+		}
+		if m.First() != first {
+			t.Errorf("deal %d: First() = %v, want %v", i+1, m.First(), first)
+		}
+		first = first.Other()
+
+		result := m.AddDeal(d.deal)
+		if result.Winner != d.deal.Winner() || result.GamePoints != d.deal.GamePoints() {
+			t.Errorf("deal %d: AddDeal() = %+v, want %v with %d game points", i+1, result, d.deal.Winner(), d.deal.GamePoints())
+		}
+		for _, p := range []Player{PlayerOne, PlayerTwo} {
+			if got := m.GamePoints(p); got != d.gamePoints[p] {
+				t.Errorf("deal %d: GamePoints(%v) = %d, want %d", i+1, p, got, d.gamePoints[p])
+			}
+		}
+	}
+
+	if !m.IsOver() {
+		t.Fatal("the match is not over with 11 game points")
+	}
+	if m.Winner() != PlayerOne {
+		t.Errorf("Winner() = %v, want %v", m.Winner(), PlayerOne)
+	}
+	if got := len(m.Deals()); got != len(deals) {
+		t.Errorf("Deals() has %d deals, want %d", got, len(deals))
+	}
+}
+
+func TestMatchWonWithMoreThanEleven(t *testing.T) {
+	m := NewMatch(PlayerOne, Rules{})
+	for i := 0; i < 3; i++ {
+		m.AddDeal(finishedDeal(PlayerTwo, 0, 0))
+	}
+	if m.IsOver() {
+		t.Fatal("the match is over with 9 game points")
+	}
+
+	m.AddDeal(finishedDeal(PlayerTwo, 0, 0))
+	if !m.IsOver() || m.Winner() != PlayerTwo || m.GamePoints(PlayerTwo) != 12 {
+		t.Errorf("the match is not won by %v with 12 game points", PlayerTwo)
+	}
+}
+
+func TestMatchRejectsDealsWhenOver(t *testing.T) {
+	m := NewMatch(PlayerOne, Rules{})
+	for !m.IsOver() {
+		m.AddDeal(finishedDeal(PlayerOne, 0, 0))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("NewDeal did not panic after the match is over")
+		}
+	}()
+	m.NewDeal(NewDeck(rand.New(rand.NewSource(1))))
+}
@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	santase "github.com/nvlbg/santase-ai"
+)
+
+// Cards are written in a compact notation of two characters - the rank
+// (one of 9, J, Q, K, T, A) followed by the suit (one of C, D, H, S).
+// For example "TH" is the ten of hearts and "9S" is the nine of spades.
+const (
+	rankChars = "9JQKTA"
+	suitChars = "CDHS"
+)
+
+// FormatCard returns the notation of the card.
+func FormatCard(c santase.Card) string {
+	return string([]byte{rankChars[c.Rank], suitChars[c.Suit]})
+}
+
+// FormatSuit returns the letter of the suit in the notation of the cards.
+func FormatSuit(s santase.Suit) string {
+	return string(suitChars[s])
+}
+
+// ParseSuit parses a suit written as its letter in the notation of the
+// cards.
+func ParseSuit(s string) (santase.Suit, error) {
+	suit := strings.Index(suitChars, strings.ToUpper(s))
+	if len(s) != 1 || suit < 0 {
+		return 0, fmt.Errorf("invalid suit %q", s)
+	}
+	return santase.Suit(suit), nil
+}
+
+// ParseCard parses a card written in the notation returned by FormatCard.
+// Lowercase letters are accepted as well.
+func ParseCard(s string) (santase.Card, error) {
+	upper := strings.ToUpper(s)
+	if len(upper) != 2 {
+		return santase.Card{}, fmt.Errorf("invalid card %q", s)
+	}
+
+	rank := strings.IndexByte(rankChars, upper[0])
+	suit := strings.IndexByte(suitChars, upper[1])
+	if rank < 0 || suit < 0 {
+		return santase.Card{}, fmt.Errorf("invalid card %q", s)
+	}
+
+	return santase.NewCard(santase.Rank(rank), santase.Suit(suit)), nil
+}
+
+// FormatDeck returns the notation of all cards in the deck written one
+// after another, for example "9CAHTS...".
+func FormatDeck(deck []santase.Card) string {
+	var b strings.Builder
+	for _, card := range deck {
+		b.WriteString(FormatCard(card))
+	}
+	return b.String()
+}
+
+// ParseDeck parses a deck written in the notation returned by FormatDeck.
+// The deck must contain every card exactly once.
+func ParseDeck(s string) ([]santase.Card, error) {
+	if len(s) != 2*len(santase.AllCards) {
+		return nil, fmt.Errorf("deck must contain %d cards", len(santase.AllCards))
+	}
+	return ParseCards(s)
+}
+
+// ParseCards parses any number of different cards written one after
+// another like the cards of a deck.
+func ParseCards(s string) ([]santase.Card, error) {
+	if len(s)%2 != 0 {
+		return nil, fmt.Errorf("invalid cards %q", s)
+	}
+
+	cards := make([]santase.Card, 0, len(s)/2)
+	seen := santase.NewPile()
+	for i := 0; i < len(s); i += 2 {
+		card, err := ParseCard(s[i : i+2])
+		if err != nil {
+			return nil, err
+		}
+		if seen.HasCard(card) {
+			return nil, fmt.Errorf("card %s is given more than once", FormatCard(card))
+		}
+		seen.AddCard(card)
+		cards = append(cards, card)
+	}
+
+	return cards, nil
+}
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	santase "github.com/nvlbg/santase-ai"
+)
+
+func TestParseCard(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    santase.Card
+		wantErr bool
+	}{
+		{in: "TH", want: santase.NewCard(santase.Ten, santase.Hearts)},
+		{in: "9S", want: santase.NewCard(santase.Nine, santase.Spades)},
+		{in: "ac", want: santase.NewCard(santase.Ace, santase.Clubs)},
+		{in: "Qd", want: santase.NewCard(santase.Queen, santase.Diamonds)},
+		{in: "", wantErr: true},
+		{in: "T", wantErr: true},
+		{in: "THS", wantErr: true},
+		{in: "8H", wantErr: true},
+		{in: "10H", wantErr: true},
+		{in: "TX", wantErr: true},
+		{in: "HT", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseCard(test.in)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("ParseCard(%q) = %v, want an error", test.in, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("ParseCard(%q) = %v, %v, want %v", test.in, got, err, test.want)
+		}
+	}
+}
+
+func TestParseDeckRoundTrip(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		deck := NewDeck(rand.New(rand.NewSource(seed)))
+		got, err := ParseDeck(FormatDeck(deck))
+		if err != nil {
+			t.Fatalf("ParseDeck(%s): %v", FormatDeck(deck), err)
+		}
+		if !reflect.DeepEqual(got, deck) {
+			t.Errorf("ParseDeck(%s) = %v, want %v", FormatDeck(deck), got, deck)
+		}
+	}
+}
+
+func TestParseDeckErrors(t *testing.T) {
+	deck := FormatDeck(NewDeck(rand.New(rand.NewSource(1))))
+
+	tests := []struct {
+		name string
+		in   string
+		err  string
+	}{
+		{"empty", "", "must contain 24 cards"},
+		{"missing card", deck[:46], "must contain 24 cards"},
+		{"extra card", deck + deck[:2], "must contain 24 cards"},
+		{"odd length", deck[:47], "must contain 24 cards"},
+		{"duplicate card", deck[:46] + deck[:2], "more than once"},
+		{"unknown rank", "8C" + deck[2:], "invalid card"},
+		{"unknown suit", deck[:2] + deck[2:3] + "X" + deck[4:], "invalid card"},
+		{"card split", "C" + deck[:47], "invalid card"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseDeck(test.in)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("ParseDeck(%q) = %v, want an error containing %q", test.in, err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	santase "github.com/nvlbg/santase-ai"
+)
+
+// RecordVersion is the version of the record format written by
+// MarshalJSON. Records of newer versions are rejected when read.
+const RecordVersion = 1
+
+// Moves are written in a compact notation which extends the notation of
+// the cards. The card is preceded by ~ if the trump card is switched and
+// by # if the game is closed, and it is followed by + if a marriage is
+// announced and by ! if the player claims 66. A claim without an
+// announcement is written without a card. For example "~#QS+" switches
+// the trump card, closes the game and announces the marriage of spades
+// with the queen, and "!" claims 66.
+const (
+	switchChar   = '~'
+	closeChar    = '#'
+	announceChar = '+'
+	claimChar    = '!'
+)
+
+// FormatMove returns the notation of the move.
+func FormatMove(m Move) string {
+	var b strings.Builder
+	if m.SwitchTrumpCard {
+		b.WriteByte(switchChar)
+	}
+	if m.CloseGame {
+		b.WriteByte(closeChar)
+	}
+	if !m.Claim || m.IsAnnouncement {
+		b.WriteString(FormatCard(m.Card))
+	}
+	if m.IsAnnouncement {
+		b.WriteByte(announceChar)
+	}
+	if m.Claim {
+		b.WriteByte(claimChar)
+	}
+	return b.String()
+}
+
+// ParseMove parses a move written in the notation returned by FormatMove.
+func ParseMove(s string) (Move, error) {
+	var m Move
+	rest := s
+	if strings.HasPrefix(rest, string(switchChar)) {
+		m.SwitchTrumpCard = true
+		rest = rest[1:]
+	}
+	if strings.HasPrefix(rest, string(closeChar)) {
+		m.CloseGame = true
+		rest = rest[1:]
+	}
+	if strings.HasSuffix(rest, string(claimChar)) {
+		m.Claim = true
+		rest = rest[:len(rest)-1]
+	}
+	if strings.HasSuffix(rest, string(announceChar)) {
+		m.IsAnnouncement = true
+		rest = rest[:len(rest)-1]
+	}
+
+	if rest == "" && m.Claim && !m.IsAnnouncement {
+		return m, nil
+	}
+	card, err := ParseCard(rest)
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid move %q", s)
+	}
+	m.Card = card
+	return m, nil
+}
+
+// Record holds everything needed to play a deal again - its rules, the
+// shuffled deck, the player that plays first and the moves played so far.
+// The order in which cards are drawn follows from the deck.
+type Record struct {
+	Rules Rules
+	Deck  []santase.Card
+	First Player
+	Moves []Move
+}
+
+// NewRecord creates a record of a deal in which no moves are played yet.
+func NewRecord(deck []santase.Card, first Player, rules Rules) *Record {
+	return &Record{
+		Rules: rules,
+		Deck:  append([]santase.Card(nil), deck...),
+		First: first,
+	}
+}
+
+// Add appends a move to the record.
+func (r *Record) Add(m Move) {
+	r.Moves = append(r.Moves, m)
+}
+
+// InitialState returns the state of the deal before any moves are played.
+func (r *Record) InitialState() State {
+	return NewState(r.Deck, r.First, r.Rules)
+}
+
+// Replay plays the moves of the record and returns the resulting state
+// together with the views of the deal for the agents, indexed by player.
+// The views are brought up to date with the moves as if the agents had
+// played them, so they can continue the deal. A nil agent gets a nil view.
+func (r *Record) Replay(agents [2]santase.Agent) (State, [2]*santase.Game, error) {
+	s := r.InitialState()
+	var views [2]*santase.Game
+	for p, agent := range agents {
+		if agent != nil {
+			views[p] = NewAgentView(s, Player(p), agent)
+		}
+	}
+
+	for i, m := range r.Moves {
+		p := s.ToMove()
+		next, events, err := s.Apply(m)
+		if err != nil {
+			return s, views, fmt.Errorf("move %d (%s): %w", i+1, FormatMove(m), err)
+		}
+
+		// the deal is over after a claim so the views do not matter
+		if view := views[p]; view != nil && !m.Claim {
+			view.SetAgent(scriptedAgent{agentMove(m, events)})
+			view.GetMove()
+			view.SetAgent(agents[p])
+		}
+		s = next
+		UpdateAgents(agents, views, events)
+	}
+
+	return s, views, nil
+}
+
+// scriptedAgent makes a view play a move that was chosen beforehand.
+type scriptedAgent struct {
+	move santase.Move
+}
+
+func (a scriptedAgent) GetMove(game *santase.Game) santase.Move {
+	return a.move
+}
+
+type jsonRecord struct {
+	Version int         `json:"version"`
+	Rules   jsonRules   `json:"rules"`
+	First   int         `json:"first"`
+	Deck    string      `json:"deck"`
+	Moves   string      `json:"moves"`
+	Result  *jsonResult `json:"result,omitempty"`
+}
+
+type jsonRules struct {
+	AutoClaim bool `json:"autoClaim"`
+}
+
+// jsonResult is written for the readers of finished records and ignored
+// when a record is read.
+type jsonResult struct {
+	Winner     int    `json:"winner"`
+	GamePoints int    `json:"gamePoints"`
+	Scores     [2]int `json:"scores"`
+}
+
+// MarshalJSON encodes the record in a human readable form in which the
+// deck and the moves are written in the compact notation and the players
+// are numbered from 1. The result of the deal is included if it is over.
+func (r *Record) MarshalJSON() ([]byte, error) {
+	moves := make([]string, len(r.Moves))
+	for i, m := range r.Moves {
+		moves[i] = FormatMove(m)
+	}
+
+	out := jsonRecord{
+		Version: RecordVersion,
+		Rules:   jsonRules{AutoClaim: r.Rules.AutoClaim},
+		First:   int(r.First) + 1,
+		Deck:    FormatDeck(r.Deck),
+		Moves:   strings.Join(moves, " "),
+	}
+
+	if s, _, err := r.Replay([2]santase.Agent{}); err == nil && s.IsOver() {
+		result := s.Result()
+		out.Result = &jsonResult{
+			Winner:     int(result.Winner) + 1,
+			GamePoints: result.GamePoints,
+			Scores:     result.Scores,
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a record encoded with MarshalJSON. The moves are
+// parsed but not checked against the rules; use Replay for that.
+func (r *Record) UnmarshalJSON(data []byte) error {
+	var in jsonRecord
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	if in.Version < 1 || in.Version > RecordVersion {
+		return fmt.Errorf("unsupported record version %d", in.Version)
+	}
+	if in.First != 1 && in.First != 2 {
+		return fmt.Errorf("invalid first player %d, expected 1 or 2", in.First)
+	}
+	deck, err := ParseDeck(in.Deck)
+	if err != nil {
+		return err
+	}
+
+	var moves []Move
+	for _, field := range strings.Fields(in.Moves) {
+		m, err := ParseMove(field)
+		if err != nil {
+			return err
+		}
+		moves = append(moves, m)
+	}
+
+	*r = Record{
+		Rules: Rules{AutoClaim: in.Rules.AutoClaim},
+		Deck:  deck,
+		First: Player(in.First - 1),
+		Moves: moves,
+	}
+	return nil
+}
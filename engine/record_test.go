@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	santase "github.com/nvlbg/santase-ai"
+)
+
+func TestParseMove(t *testing.T) {
+	qs := santase.NewCard(santase.Queen, santase.Spades)
+	tests := []struct {
+		in   string
+		want Move
+	}{
+		{"QS", Move{Card: qs}},
+		{"~QS", Move{Card: qs, SwitchTrumpCard: true}},
+		{"#QS", Move{Card: qs, CloseGame: true}},
+		{"QS+", Move{Card: qs, IsAnnouncement: true}},
+		{"~#QS+", Move{Card: qs, SwitchTrumpCard: true, CloseGame: true, IsAnnouncement: true}},
+		{"!", Move{Claim: true}},
+		{"QS+!", Move{Card: qs, IsAnnouncement: true, Claim: true}},
+		{"~#QS+!", Move{Card: qs, SwitchTrumpCard: true, CloseGame: true, IsAnnouncement: true, Claim: true}},
+	}
+
+	for _, test := range tests {
+		got, err := ParseMove(test.in)
+		if err != nil || got != test.want {
+			t.Errorf("ParseMove(%q) = %+v, %v, want %+v", test.in, got, err, test.want)
+		}
+		if s := FormatMove(test.want); s != test.in {
+			t.Errorf("FormatMove(%+v) = %q, want %q", test.want, s, test.in)
+		}
+	}
+
+	if got, err := ParseMove("~qs+"); err != nil || got != (Move{Card: qs, SwitchTrumpCard: true, IsAnnouncement: true}) {
+		t.Errorf("ParseMove(%q) = %+v, %v, want the lowercase card accepted", "~qs+", got, err)
+	}
+}
+
+func TestParseMoveErrors(t *testing.T) {
+	for _, in := range []string{
+		"", "~", "#", "+", "~#", "QS~", "QS#", "#~QS", "+QS", "QS!+",
+		"QS++", "~~QS", "!!", "Q", "QSS", "XS+", "QX",
+	} {
+		if m, err := ParseMove(in); err == nil {
+			t.Errorf("ParseMove(%q) = %+v, want an error", in, m)
+		}
+	}
+}
+
+// playedRecord returns the record of a deal played to the end with the
+// lowest legal card on every turn, or of its first n moves if n is not
+// negative.
+func playedRecord(seed int64, n int) *Record {
+	record := NewRecord(NewDeck(rand.New(rand.NewSource(seed))), PlayerTwo, Rules{})
+	s := record.InitialState()
+	for !s.IsOver() && n != 0 {
+		m := playCard(s)
+		s, _, _ = s.Apply(m)
+		record.Add(m)
+		n--
+	}
+	return record
+}
+
+func TestRecordJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		record *Record
+	}{
+		{"no moves", playedRecord(1, 0)},
+		{"in progress", playedRecord(2, 7)},
+		{"finished", playedRecord(3, -1)},
+		{"auto claim", &Record{Rules: Rules{AutoClaim: true}, Deck: playedRecord(4, 0).Deck}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := json.Marshal(test.record)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got Record
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal(%s): %v", data, err)
+			}
+			if !reflect.DeepEqual(&got, test.record) {
+				t.Errorf("Unmarshal(%s) = %+v, want %+v", data, got, *test.record)
+			}
+
+			s, _, err := got.Replay([2]santase.Agent{})
+			if err != nil {
+				t.Fatalf("Replay: %v", err)
+			}
+			if hasResult := strings.Contains(string(data), `"result"`); hasResult != s.IsOver() {
+				t.Errorf("%s has a result: %v, want %v", data, hasResult, s.IsOver())
+			}
+		})
+	}
+}
+
+func TestRecordUnmarshalErrors(t *testing.T) {
+	deck := FormatDeck(playedRecord(1, 0).Deck)
+
+	tests := []struct {
+		name string
+		json string
+		err  string
+	}{
+		{"unknown version", `{"version": 2, "first": 1, "deck": "` + deck + `"}`, "unsupported record version 2"},
+		{"no version", `{"first": 1, "deck": "` + deck + `"}`, "unsupported record version 0"},
+		{"invalid first player", `{"version": 1, "first": 3, "deck": "` + deck + `"}`, "invalid first player"},
+		{"incomplete deck", `{"version": 1, "first": 1, "deck": "` + deck[:46] + `"}`, "deck must contain"},
+		{"invalid move", `{"version": 1, "first": 1, "deck": "` + deck + `", "moves": "QS QS?"}`, "invalid move"},
+		{"not an object", `[1, 2]`, "cannot unmarshal"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var r Record
+			err := json.Unmarshal([]byte(test.json), &r)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Unmarshal(%s) = %v, want an error containing %q", test.json, err, test.err)
+			}
+		})
+	}
+}
+
+func TestRecordReplayRejectsIllegalMove(t *testing.T) {
+	record := playedRecord(5, 4)
+	s := record.InitialState()
+
+	tests := []struct {
+		name string
+		move Move
+		err  error
+	}{
+		{"card of the opponent", Move{Card: s.SortedHand(PlayerOne)[0]}, ErrCardNotInHand},
+		{"closing on the first lead", Move{Card: s.SortedHand(PlayerTwo)[0], CloseGame: true}, ErrCannotClose},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := *record
+			r.Moves = append([]Move{test.move}, record.Moves...)
+
+			data, err := json.Marshal(&r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var loaded Record
+			if err := json.Unmarshal(data, &loaded); err != nil {
+				t.Fatalf("Unmarshal(%s): %v", data, err)
+			}
+
+			_, _, err = loaded.Replay([2]santase.Agent{})
+			if !errors.Is(err, test.err) || !strings.HasPrefix(err.Error(), "move 1 ") {
+				t.Errorf("Replay() = %v, want %v for move 1", err, test.err)
+			}
+		})
+	}
+}
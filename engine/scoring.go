@@ -0,0 +1,57 @@
+package engine
+
+// GamePoints returns the number of game points the winner of the deal
+// receives. The points depend on how well the loser did:
+//
+//	3 game points if the loser has not taken a trick
+//	2 game points if the loser has less than 33 points
+//	1 game point otherwise
+//
+// A player that claims to have 66 points but has less loses and their
+// opponent receives 3 game points.
+//
+// If the game was closed the points are determined by the state of the
+// deal at the moment of closing:
+//
+//	if the player that closed the game won, the points are calculated as
+//	above but from the tricks and points their opponent had when the
+//	game was closed
+//
+//	if the player that closed the game lost (they did not reach 66 or
+//	their opponent reached 66 first), their opponent receives 3 game
+//	points
+//
+// The latter follows the variant of the rules in which a failed closing
+// always costs 3 game points, not the one in which the opponent receives
+// the game points the state of the closer at closing would give.
+//
+// Panics if the deal is not over yet.
+func (s State) GamePoints() int {
+	winner := s.Winner()
+	loser := winner.Other()
+
+	if s.claimed && !s.claimValid {
+		return 3
+	}
+
+	if s.isClosed {
+		if s.closedBy == loser {
+			return 3
+		}
+		return gamePoints(s.closingTricks[loser], s.closingScores[loser])
+	}
+
+	return gamePoints(s.tricks[loser], s.scores[loser])
+}
+
+func gamePoints(loserTricks, loserScore int) int {
+	if loserTricks == 0 {
+		return 3
+	}
+
+	if loserScore < 33 {
+		return 2
+	}
+
+	return 1
+}
@@ -0,0 +1,123 @@
+package engine
+
+import "testing"
+
+func TestGamePoints(t *testing.T) {
+	tests := []struct {
+		name  string
+		state State
+		want  int
+	}{
+		{
+			name: "loser without tricks",
+			state: State{
+				isOver: true, winner: PlayerOne,
+				scores: [2]int{70, 0}, tricks: [2]int{6, 0},
+			},
+			want: 3,
+		},
+		{
+			name: "loser under 33",
+			state: State{
+				isOver: true, winner: PlayerOne,
+				scores: [2]int{66, 32}, tricks: [2]int{4, 2},
+			},
+			want: 2,
+		},
+		{
+			name: "loser with 33",
+			state: State{
+				isOver: true, winner: PlayerTwo,
+				scores: [2]int{33, 67}, tricks: [2]int{3, 3},
+			},
+			want: 1,
+		},
+		{
+			name: "false claim",
+			state: State{
+				isOver: true, winner: PlayerTwo,
+				claimed: true, claimedBy: PlayerOne, claimValid: false,
+				scores: [2]int{60, 50}, tricks: [2]int{3, 3},
+			},
+			want: 3,
+		},
+		{
+			name: "closer reaches 66",
+			state: State{
+				isOver: true, winner: PlayerOne,
+				isClosed: true, closedBy: PlayerOne,
+				closingScores: [2]int{40, 35}, closingTricks: [2]int{2, 2},
+				scores: [2]int{68, 35}, tricks: [2]int{4, 2},
+			},
+			want: 1,
+		},
+		{
+			name: "closer fails",
+			state: State{
+				isOver: true, winner: PlayerTwo,
+				isClosed: true, closedBy: PlayerOne,
+				closingScores: [2]int{50, 10}, closingTricks: [2]int{3, 1},
+				scores: [2]int{62, 58}, tricks: [2]int{4, 5},
+			},
+			want: 3,
+		},
+		{
+			name: "closer fails against an opponent without tricks",
+			state: State{
+				isOver: true, winner: PlayerOne,
+				isClosed: true, closedBy: PlayerTwo,
+				closingScores: [2]int{0, 45}, closingTricks: [2]int{0, 3},
+				scores: [2]int{30, 60}, tricks: [2]int{2, 4},
+			},
+			want: 3,
+		},
+		{
+			name: "closed by the player that did not start the deal",
+			state: State{
+				isOver: true, winner: PlayerTwo,
+				isClosed: true, closedBy: PlayerTwo,
+				closingScores: [2]int{25, 41}, closingTricks: [2]int{2, 2},
+				scores: [2]int{25, 70}, tricks: [2]int{2, 5},
+			},
+			want: 2,
+		},
+		{
+			name: "opponent without tricks when closing",
+			state: State{
+				isOver: true, winner: PlayerOne,
+				isClosed: true, closedBy: PlayerOne,
+				closingScores: [2]int{30, 0}, closingTricks: [2]int{2, 0},
+				scores: [2]int{66, 24}, tricks: [2]int{4, 2},
+			},
+			want: 3,
+		},
+		{
+			name: "opponent score frozen when closing",
+			state: State{
+				isOver: true, winner: PlayerOne,
+				isClosed: true, closedBy: PlayerOne,
+				closingScores: [2]int{45, 20}, closingTricks: [2]int{3, 1},
+				scores: [2]int{66, 40}, tricks: [2]int{5, 3},
+			},
+			want: 2,
+		},
+		{
+			name: "opponent over 33 when closing",
+			state: State{
+				isOver: true, winner: PlayerTwo,
+				isClosed: true, closedBy: PlayerTwo,
+				closingScores: [2]int{33, 50}, closingTricks: [2]int{2, 3},
+				scores: [2]int{33, 77}, tricks: [2]int{2, 6},
+			},
+			want: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.state.GamePoints(); got != test.want {
+				t.Errorf("GamePoints() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,546 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	santase "github.com/nvlbg/santase-ai"
+)
+
+// LastTrickBonus is the number of points awarded to the player that wins
+// the last trick after all cards have been drawn. The bonus is not
+// awarded if the game has been closed.
+const LastTrickBonus = 10
+
+// Errors returned by Apply when a move is not legal.
+var (
+	ErrGameOver              = errors.New("the game is over")
+	ErrCardNotInHand         = errors.New("card is not in hand")
+	ErrIllegalResponse       = errors.New("illegal response")
+	ErrCannotSwitchTrumpCard = errors.New("cannot switch trump card")
+	ErrCannotClose           = errors.New("cannot close the game")
+	ErrInvalidAnnouncement   = errors.New("invalid announcement")
+	ErrCannotClaim           = errors.New("cannot claim 66 when not leading")
+)
+
+// State is the complete state of a single deal. It is a value type -
+// every method that changes the deal returns a new State and leaves
+// the receiver untouched.
+type State struct {
+	rules     Rules
+	trump     santase.Suit
+	trumpCard *santase.Card
+	stack     []santase.Card
+	hands     [2]santase.Hand
+	scores    [2]int
+	tricks    [2]int
+
+	// points of the marriages announced by players that have not taken a
+	// trick yet, which count only once they take one
+	pending    [2]int
+	cardPlayed *santase.Card
+	leader     Player
+	toMove     Player
+	lastTrick  Player
+	isClosed   bool
+	closedBy   Player
+	isOver     bool
+	winner     Player
+	claimed    bool
+	claimedBy  Player
+	claimValid bool
+
+	// scores and tricks of the players at the moment the game was closed
+	closingScores [2]int
+	closingTricks [2]int
+
+	// suits of the marriages announced by each player in the order they
+	// were announced
+	marriages [2][]santase.Suit
+
+	// cards of the completed tricks in the order they were played
+	played []santase.Card
+}
+
+// NewState deals the passed deck and returns the initial state of the
+// deal. The first six cards go to PlayerOne, the next six to PlayerTwo,
+// the thirteenth card is the trump card and the rest of the deck forms
+// the stack, its last card being the top one.
+//
+// The deal is started by the passed player and played with the passed
+// optional rules.
+//
+// Panics if the deck does not contain all cards exactly once.
+func NewState(deck []santase.Card, first Player, rules Rules) State {
+	if len(deck) != len(santase.AllCards) {
+		panic("deck is not complete")
+	}
+	seen := santase.NewPile()
+	for _, card := range deck {
+		if seen.HasCard(card) {
+			panic("deck contains duplicate cards")
+		}
+		seen.AddCard(card)
+	}
+
+	trumpCard := deck[12]
+	stack := make([]santase.Card, len(deck)-13)
+	copy(stack, deck[13:])
+
+	return State{
+		rules:     rules,
+		trump:     trumpCard.Suit,
+		trumpCard: &trumpCard,
+		stack:     stack,
+		hands: [2]santase.Hand{
+			santase.NewHand(deck[:6]...),
+			santase.NewHand(deck[6:12]...),
+		},
+		toMove: first,
+	}
+}
+
+// Rules returns the optional rules the deal is played with.
+func (s State) Rules() Rules {
+	return s.rules
+}
+
+// Trump returns the trump suit of the deal.
+func (s State) Trump() santase.Suit {
+	return s.trump
+}
+
+// TrumpCard returns the trump card placed under the stack. If it has
+// been drawn already the result is nil.
+func (s State) TrumpCard() *santase.Card {
+	if s.trumpCard == nil {
+		return nil
+	}
+	card := *s.trumpCard
+	return &card
+}
+
+// Stack returns the cards left to be drawn without the trump card.
+// The last card of the result is the one on top of the stack.
+func (s State) Stack() []santase.Card {
+	stack := make([]santase.Card, len(s.stack))
+	copy(stack, s.stack)
+	return stack
+}
+
+// Hand returns the cards held by the player.
+func (s State) Hand(p Player) santase.Hand {
+	return s.hands[p].Clone()
+}
+
+// SortedHand returns the cards held by the player sorted by suit and rank.
+func (s State) SortedHand(p Player) []santase.Card {
+	return sortCards(s.hands[p].ToSlice())
+}
+
+// Played returns the cards of the completed tricks in the order they were
+// played.
+func (s State) Played() []santase.Card {
+	played := make([]santase.Card, len(s.played))
+	copy(played, s.played)
+	return played
+}
+
+// Score returns the points the player has collected in the deal.
+func (s State) Score(p Player) int {
+	return s.scores[p]
+}
+
+// PendingScore returns the points of the marriages the player has
+// announced before taking a trick. They are added to the score when the
+// player takes their first trick and are lost if they take none.
+func (s State) PendingScore(p Player) int {
+	return s.pending[p]
+}
+
+// Marriages returns the suits of the marriages the player has announced in
+// the order they were announced.
+func (s State) Marriages(p Player) []santase.Suit {
+	marriages := make([]santase.Suit, len(s.marriages[p]))
+	copy(marriages, s.marriages[p])
+	return marriages
+}
+
+// Tricks returns the number of tricks the player has taken.
+func (s State) Tricks(p Player) int {
+	return s.tricks[p]
+}
+
+// CardPlayed returns the card placed on the table by the player that
+// leads the current trick. If there is no such card the result is nil.
+func (s State) CardPlayed() *santase.Card {
+	if s.cardPlayed == nil {
+		return nil
+	}
+	card := *s.cardPlayed
+	return &card
+}
+
+// Leader returns the player that leads (or is about to lead) the
+// current trick.
+func (s State) Leader() Player {
+	if s.cardPlayed == nil {
+		return s.toMove
+	}
+	return s.leader
+}
+
+// ToMove returns the player whose turn it is.
+func (s State) ToMove() Player {
+	return s.toMove
+}
+
+// IsClosed returns whether the game has been closed by one of the players.
+func (s State) IsClosed() bool {
+	return s.isClosed
+}
+
+// ClosedBy returns the player that closed the game. The result is only
+// meaningful if the game is closed.
+func (s State) ClosedBy() Player {
+	return s.closedBy
+}
+
+// ClosingScore returns the points the player had at the moment the game
+// was closed. The result is only meaningful if the game is closed.
+func (s State) ClosingScore(p Player) int {
+	return s.closingScores[p]
+}
+
+// ClosingTricks returns the number of tricks the player had taken at the
+// moment the game was closed. The result is only meaningful if the game
+// is closed.
+func (s State) ClosingTricks(p Player) int {
+	return s.closingTricks[p]
+}
+
+// IsOver returns whether the deal has ended.
+func (s State) IsOver() bool {
+	return s.isOver
+}
+
+// ClaimedBy returns the player that claimed to have collected 66 points.
+// The second result is false if nobody claimed.
+func (s State) ClaimedBy() (Player, bool) {
+	return s.claimedBy, s.claimed
+}
+
+// IsClaimValid returns whether the claim for 66 points was true. The
+// result is only meaningful if one of the players claimed.
+func (s State) IsClaimValid() bool {
+	return s.claimValid
+}
+
+// Winner returns the player that won the deal.
+//
+// Panics if the deal is not over yet.
+func (s State) Winner() Player {
+	if !s.isOver {
+		panic("the game is not over")
+	}
+	return s.winner
+}
+
+// canDeclare reports whether the player to move may switch the trump card
+// or close the game. Both are allowed only to the leading player after the
+// first trick and while there are more than two cards left to draw.
+func (s State) canDeclare() bool {
+	return !s.isOver && !s.isClosed && s.cardPlayed == nil && s.trumpCard != nil &&
+		len(s.stack) > 1 && len(s.stack) < 11
+}
+
+// CanSwitchTrumpCard returns whether the player to move can exchange the
+// nine of trump in their hand for the trump card.
+func (s State) CanSwitchTrumpCard() bool {
+	return s.canDeclare() && s.trumpCard.Rank != santase.Nine &&
+		s.hands[s.toMove].HasCard(santase.NewCard(santase.Nine, s.trump))
+}
+
+// CanClose returns whether the player to move can close the game.
+func (s State) CanClose() bool {
+	return s.canDeclare()
+}
+
+// CanAnnounce returns whether the player to move can announce a marriage
+// by playing the passed card. A marriage can be announced on the first
+// lead too, but its points count only once the player takes a trick.
+func (s State) CanAnnounce(card santase.Card) bool {
+	if s.isOver || s.cardPlayed != nil {
+		return false
+	}
+
+	if card.Rank != santase.Queen && card.Rank != santase.King {
+		return false
+	}
+
+	return s.hands[s.toMove].HasCard(card) && s.hands[s.toMove].HasCard(marriagePartner(card))
+}
+
+// CanClaim returns whether the player to move can claim that they have
+// collected 66 points. A claim can be made only by the leading player,
+// on its own or together with announcing a marriage.
+func (s State) CanClaim() bool {
+	return !s.isOver && s.cardPlayed == nil
+}
+
+// IsCardLegal returns whether the player to move is allowed to play the
+// passed card.
+func (s State) IsCardLegal(card santase.Card) bool {
+	if s.isOver || !s.hands[s.toMove].HasCard(card) {
+		return false
+	}
+
+	// you're first to play or the game is not closed
+	if s.cardPlayed == nil || (s.trumpCard != nil && !s.isClosed) {
+		return true
+	}
+
+	responses := s.hands[s.toMove].GetValidResponses(*s.cardPlayed, s.trump)
+	return responses.HasCard(card)
+}
+
+// LegalMoves returns the moves the player to move is allowed to make,
+// ordered by the card played: every card they may play, alone and
+// together with switching the trump card, closing the game and announcing
+// a marriage where these are allowed, followed by the claims.
+func (s State) LegalMoves() []Move {
+	if s.isOver {
+		return nil
+	}
+
+	cards := s.SortedHand(s.toMove)
+	if s.CanSwitchTrumpCard() {
+		cards = sortCards(append(cards, *s.trumpCard))
+	}
+	var candidates []Move
+	for _, card := range cards {
+		for _, switchTrumpCard := range []bool{false, true} {
+			for _, closeGame := range []bool{false, true} {
+				for _, announce := range []bool{false, true} {
+					candidates = append(candidates, Move{
+						Card:            card,
+						IsAnnouncement:  announce,
+						SwitchTrumpCard: switchTrumpCard,
+						CloseGame:       closeGame,
+					})
+				}
+			}
+		}
+	}
+	candidates = append(candidates, Move{Claim: true})
+	for _, card := range cards {
+		candidates = append(candidates, Move{Card: card, IsAnnouncement: true, Claim: true})
+	}
+
+	var moves []Move
+	for _, m := range candidates {
+		if _, _, err := s.Apply(m); err == nil {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+func marriagePartner(card santase.Card) santase.Card {
+	if card.Rank == santase.Queen {
+		return santase.NewCard(santase.King, card.Suit)
+	}
+	return santase.NewCard(santase.Queen, card.Suit)
+}
+
+func marriagePoints(card santase.Card, trump santase.Suit) int {
+	if card.Suit == trump {
+		return 40
+	}
+	return 20
+}
+
+func (s State) clone() State {
+	s.hands[PlayerOne] = s.hands[PlayerOne].Clone()
+	s.hands[PlayerTwo] = s.hands[PlayerTwo].Clone()
+	s.stack = s.Stack()
+	s.marriages[PlayerOne] = s.Marriages(PlayerOne)
+	s.marriages[PlayerTwo] = s.Marriages(PlayerTwo)
+	s.played = s.Played()
+	return s
+}
+
+// Apply plays the move on behalf of the player whose turn it is. It
+// returns the resulting state and the events that happened in the order
+// they happened. If the move is not legal an error is returned and the
+// state is left unchanged.
+func (s State) Apply(m Move) (State, []Event, error) {
+	if s.isOver {
+		return s, nil, ErrGameOver
+	}
+
+	next := s.clone()
+	p := next.toMove
+	var events []Event
+
+	if m.SwitchTrumpCard {
+		if !next.CanSwitchTrumpCard() {
+			return s, nil, ErrCannotSwitchTrumpCard
+		}
+		nineTrump := santase.NewCard(santase.Nine, next.trump)
+		trumpCard := *next.trumpCard
+		next.hands[p].RemoveCard(nineTrump)
+		next.hands[p].AddCard(trumpCard)
+		next.trumpCard = &nineTrump
+		events = append(events, TrumpSwitched{Player: p, TrumpCard: trumpCard})
+	}
+
+	if m.CloseGame {
+		if !next.CanClose() {
+			return s, nil, ErrCannotClose
+		}
+		next.isClosed = true
+		next.closedBy = p
+		next.closingScores = next.scores
+		next.closingTricks = next.tricks
+		events = append(events, GameClosed{Player: p})
+	}
+
+	if m.IsAnnouncement {
+		if !next.CanAnnounce(m.Card) {
+			return s, nil, fmt.Errorf("%w: %v", ErrInvalidAnnouncement, m.Card)
+		}
+		points := marriagePoints(m.Card, next.trump)
+		pending := next.tricks[p] == 0
+		if pending {
+			next.pending[p] += points
+		} else {
+			next.scores[p] += points
+		}
+		next.marriages[p] = append(next.marriages[p], m.Card.Suit)
+		events = append(events, Announced{Player: p, Suit: m.Card.Suit, Points: points, Pending: pending})
+
+		if next.rules.AutoClaim && next.scores[p] >= 66 {
+			events = append(events, next.finish(p))
+			return next, events, nil
+		}
+	}
+
+	if m.Claim {
+		if !next.CanClaim() {
+			return s, nil, ErrCannotClaim
+		}
+		valid := next.scores[p] >= 66
+		next.claimed = true
+		next.claimedBy = p
+		next.claimValid = valid
+		events = append(events, Claimed{Player: p, Valid: valid})
+
+		winner := p
+		if !valid {
+			winner = p.Other()
+		}
+		events = append(events, next.finish(winner))
+		return next, events, nil
+	}
+
+	if !next.hands[p].HasCard(m.Card) {
+		return s, nil, fmt.Errorf("%w: %v", ErrCardNotInHand, m.Card)
+	}
+	if !next.IsCardLegal(m.Card) {
+		return s, nil, fmt.Errorf("%w: %v", ErrIllegalResponse, m.Card)
+	}
+
+	card := m.Card
+	next.hands[p].RemoveCard(card)
+	events = append(events, CardPlayed{Player: p, Move: m})
+
+	if next.cardPlayed == nil {
+		next.cardPlayed = &card
+		next.leader = p
+		next.toMove = p.Other()
+		return next, events, nil
+	}
+
+	events = append(events, next.playTrick(card)...)
+	return next, events, nil
+}
+
+// playTrick completes the current trick with the response card.
+func (s *State) playTrick(response santase.Card) []Event {
+	lead := *s.cardPlayed
+	winner := s.leader
+	if *santase.StrongerCard(&lead, &response, s.trump) == response {
+		winner = s.leader.Other()
+	}
+
+	points := santase.Points(&lead) + santase.Points(&response)
+	marriages := s.pending[winner]
+	s.scores[winner] += points + marriages
+	s.pending[winner] = 0
+	s.tricks[winner]++
+	s.played = append(s.played, lead, response)
+	s.cardPlayed = nil
+	s.toMove = winner
+	s.lastTrick = winner
+
+	events := []Event{TrickWon{
+		Player:    winner,
+		Cards:     [2]santase.Card{lead, response},
+		Points:    points,
+		Marriages: marriages,
+	}}
+
+	isLastTrick := len(s.hands[PlayerOne]) == 0 && len(s.hands[PlayerTwo]) == 0
+	if isLastTrick && !s.isClosed {
+		s.scores[winner] += LastTrickBonus
+		events = append(events, LastTrickBonusWon{Player: winner, Points: LastTrickBonus})
+	}
+
+	// the player that takes the last trick has no chance to claim 66
+	// before the deal ends, so they win if they have collected it
+	if (s.rules.AutoClaim || isLastTrick) && s.scores[winner] >= 66 {
+		return append(events, s.finish(winner))
+	}
+
+	if isLastTrick {
+		return append(events, s.finish(s.winnerWithoutSixtySix()))
+	}
+
+	if s.trumpCard != nil && !s.isClosed {
+		events = append(events, s.drawCard(winner), s.drawCard(winner.Other()))
+	}
+
+	return events
+}
+
+// winnerWithoutSixtySix returns the winner of a deal that ended because the
+// players ran out of cards without any of them claiming 66. If the game
+// was closed the player that closed it failed, otherwise the player that
+// took the last trick wins.
+func (s *State) winnerWithoutSixtySix() Player {
+	if s.isClosed {
+		return s.closedBy.Other()
+	}
+	return s.lastTrick
+}
+
+func (s *State) drawCard(p Player) Event {
+	var card santase.Card
+	if len(s.stack) > 0 {
+		card = s.stack[len(s.stack)-1]
+		s.stack = s.stack[:len(s.stack)-1]
+	} else {
+		card = *s.trumpCard
+		s.trumpCard = nil
+	}
+
+	s.hands[p].AddCard(card)
+	return CardDrawn{Player: p, Card: card}
+}
+
+func (s *State) finish(winner Player) Event {
+	s.isOver = true
+	s.winner = winner
+	return GameOver{Winner: winner}
+}
@@ -0,0 +1,184 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+
+	santase "github.com/nvlbg/santase-ai"
+)
+
+// playOut plays the deal to the end with the move chosen by choose for
+// each turn and returns the final state and the events of all moves.
+func playOut(t *testing.T, s State, choose func(s State) Move) (State, []Event) {
+	t.Helper()
+
+	var events []Event
+	for !s.IsOver() {
+		m := choose(s)
+		next, more, err := s.Apply(m)
+		if err != nil {
+			t.Fatalf("Apply(%v): %v", m, err)
+		}
+		s = next
+		events = append(events, more...)
+	}
+	return s, events
+}
+
+// playCard plays the lowest legal card of the player to move.
+func playCard(s State) Move {
+	for _, card := range s.SortedHand(s.ToMove()) {
+		if s.IsCardLegal(card) {
+			return Move{Card: card}
+		}
+	}
+	panic("no legal card to play")
+}
+
+// closeOrPlayCard closes the game as soon as possible and otherwise plays
+// a card.
+func closeOrPlayCard(s State) Move {
+	m := playCard(s)
+	m.CloseGame = s.CanClose()
+	return m
+}
+
+func TestLastTrickBonus(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		deck := NewDeck(rand.New(rand.NewSource(seed)))
+
+		s, events := playOut(t, NewState(deck, PlayerOne, Rules{}), playCard)
+		if s.IsClosed() {
+			t.Fatalf("seed %d: the game was closed", seed)
+		}
+		if total := s.Score(PlayerOne) + s.Score(PlayerTwo); total != 120+LastTrickBonus {
+			t.Errorf("seed %d: the players have %d points in total, want %d", seed, total, 120+LastTrickBonus)
+		}
+		if !hasLastTrickBonus(events, s.Winner()) {
+			t.Errorf("seed %d: the last trick gave no bonus to %v: %v", seed, s.Winner(), events)
+		}
+	}
+}
+
+func TestNoLastTrickBonusWhenClosed(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		deck := NewDeck(rand.New(rand.NewSource(seed)))
+
+		s, events := playOut(t, NewState(deck, PlayerOne, Rules{}), closeOrPlayCard)
+		if !s.IsClosed() {
+			t.Fatalf("seed %d: the game was not closed", seed)
+		}
+		if total, want := s.Score(PlayerOne)+s.Score(PlayerTwo), trickPoints(events); total != want {
+			t.Errorf("seed %d: the players have %d points in total in a closed game, want %d", seed, total, want)
+		}
+		if hasLastTrickBonus(events, PlayerOne) || hasLastTrickBonus(events, PlayerTwo) {
+			t.Errorf("seed %d: the last trick of a closed game gave a bonus: %v", seed, events)
+		}
+	}
+}
+
+// trickPoints returns the points of the cards taken in the tricks.
+func trickPoints(events []Event) int {
+	points := 0
+	for _, e := range events {
+		if e, ok := e.(TrickWon); ok {
+			points += e.Points
+		}
+	}
+	return points
+}
+
+func hasLastTrickBonus(events []Event, p Player) bool {
+	for _, e := range events {
+		if e, ok := e.(LastTrickBonusWon); ok && e.Player == p && e.Points == LastTrickBonus {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCloserReachesSixtySixWithLastTrick(t *testing.T) {
+	// the player that closes the game has 56 points before the last trick
+	// and takes it for 14 more
+	deck := NewDeck(rand.New(rand.NewSource(153)))
+	s, events := playOut(t, NewState(deck, PlayerOne, Rules{}), closeOrPlayCard)
+
+	if !s.IsClosed() || s.ClosedBy() != PlayerOne {
+		t.Fatalf("the game was not closed by %v", PlayerOne)
+	}
+	trick, ok := events[len(events)-2].(TrickWon)
+	if !ok || trick.Player != PlayerOne || s.Score(PlayerOne)-trick.Points >= 66 || s.Score(PlayerOne) < 66 {
+		t.Fatalf("%v did not reach 66 with the last trick: %v", PlayerOne, events)
+	}
+	if s.Winner() != PlayerOne {
+		t.Errorf("Winner() = %v, want %v", s.Winner(), PlayerOne)
+	}
+	if want := gamePoints(s.ClosingTricks(PlayerTwo), s.ClosingScore(PlayerTwo)); s.GamePoints() != want {
+		t.Errorf("GamePoints() = %d, want %d", s.GamePoints(), want)
+	}
+}
+
+// applyMoves plays the moves of the record from its initial state and
+// returns the resulting state and the events of all moves.
+func applyMoves(t *testing.T, record *Record) (State, []Event) {
+	t.Helper()
+
+	s := record.InitialState()
+	var events []Event
+	for _, m := range record.Moves {
+		next, more, err := s.Apply(m)
+		if err != nil {
+			t.Fatalf("Apply(%s): %v", FormatMove(m), err)
+		}
+		s = next
+		events = append(events, more...)
+	}
+	return s, events
+}
+
+func TestFirstLeadMarriageCountsAfterTrick(t *testing.T) {
+	s, events := applyMoves(t, newTestRecord(t, firstLeadDeck, "QS+"))
+	if len(events) != 2 || events[0] != (Announced{Player: PlayerOne, Suit: santase.Spades, Points: 20, Pending: true}) {
+		t.Fatalf("events of the announcement = %v, want a pending Announced", events)
+	}
+	if s.Score(PlayerOne) != 0 || s.PendingScore(PlayerOne) != 20 {
+		t.Errorf("score after the announcement = %d+%d pending, want 0+20", s.Score(PlayerOne), s.PendingScore(PlayerOne))
+	}
+
+	// PlayerOne loses the first trick and takes the second one with the
+	// king of spades
+	s, events = applyMoves(t, newTestRecord(t, firstLeadDeck, "QS+ AS JS KS"))
+	if s.Score(PlayerOne) != 26 || s.PendingScore(PlayerOne) != 0 {
+		t.Errorf("score after the second trick = %d+%d pending, want 26+0", s.Score(PlayerOne), s.PendingScore(PlayerOne))
+	}
+	if s.Score(PlayerTwo) != 14 {
+		t.Errorf("score of %v = %d, want 14", PlayerTwo, s.Score(PlayerTwo))
+	}
+	var won []TrickWon
+	for _, e := range events {
+		if e, ok := e.(TrickWon); ok {
+			won = append(won, e)
+		}
+	}
+	if len(won) != 2 || won[0].Marriages != 0 || won[1].Player != PlayerOne || won[1].Marriages != 20 {
+		t.Errorf("tricks won = %v, want the second one with the marriage of %v", won, PlayerOne)
+	}
+}
+
+func TestFirstLeadMarriageLostWithoutTrick(t *testing.T) {
+	// PlayerTwo takes every trick and claims with the marriage in trumps
+	// before PlayerOne takes any
+	s, _ := applyMoves(t, newTestRecord(t, firstLeadDeck, "QS+ AS AH 9C TH JC KH+!"))
+	if !s.IsOver() {
+		t.Fatal("the deal is not over after the claim")
+	}
+	if !s.IsClaimValid() || s.Winner() != PlayerTwo {
+		t.Errorf("the claim of %v with %d points is not valid", PlayerTwo, s.Score(PlayerTwo))
+	}
+	if s.Tricks(PlayerOne) != 0 || s.Score(PlayerOne) != 0 {
+		t.Errorf("%v has %d tricks and %d points, want none", PlayerOne, s.Tricks(PlayerOne), s.Score(PlayerOne))
+	}
+	if s.GamePoints() != 3 {
+		t.Errorf("GamePoints() = %d, want 3", s.GamePoints())
+	}
+}
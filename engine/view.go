@@ -0,0 +1,449 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	santase "github.com/nvlbg/santase-ai"
+)
+
+// View is what a player can see of a deal: everything but the cards in the
+// hand of their opponent and in the stack, of which only the number is
+// known. In a view the player is always PlayerOne and their opponent
+// PlayerTwo, so that a front end can show it the same way to both players.
+//
+// A public view is what a spectator sees who knows neither hand. It is
+// the view of PlayerOne without their hand.
+type View struct {
+	Rules Rules
+
+	// Hand is the hand of the player sorted by suit and rank; it is empty
+	// in a public view, in which HiddenHand is the number of its cards
+	Hand       []santase.Card
+	HiddenHand int
+
+	// OpponentCards and Stack are the number of cards in the hand of the
+	// opponent and in the stack without the trump card
+	OpponentCards int
+	Stack         int
+
+	// Played holds the cards of the completed tricks in the order they
+	// were played
+	Played []santase.Card
+
+	Trump      santase.Suit
+	TrumpCard  *santase.Card
+	CardPlayed *santase.Card
+	Leader     Player
+	ToMove     Player
+	LastTrick  Player
+
+	Scores    [2]int
+	Tricks    [2]int
+	Pending   [2]int
+	Marriages [2][]santase.Suit
+
+	Closed        bool
+	ClosedBy      Player
+	ClosingScores [2]int
+	ClosingTricks [2]int
+
+	Over       bool
+	Winner     Player
+	Claimed    bool
+	ClaimedBy  Player
+	ClaimValid bool
+}
+
+// View returns what the player can see of the deal.
+func (s State) View(p Player) View {
+	// seat converts a player to the seat they have in the view
+	seat := func(q Player) Player {
+		if p == PlayerOne {
+			return q
+		}
+		return q.Other()
+	}
+	pair := func(values [2]int) [2]int {
+		return [2]int{values[p], values[p.Other()]}
+	}
+
+	return View{
+		Rules:         s.rules,
+		Hand:          s.SortedHand(p),
+		OpponentCards: len(s.hands[p.Other()]),
+		Stack:         len(s.stack),
+		Played:        s.Played(),
+		Trump:         s.trump,
+		TrumpCard:     s.TrumpCard(),
+		CardPlayed:    s.CardPlayed(),
+		Leader:        seat(s.Leader()),
+		ToMove:        seat(s.toMove),
+		LastTrick:     seat(s.lastTrick),
+		Scores:        pair(s.scores),
+		Tricks:        pair(s.tricks),
+		Pending:       pair(s.pending),
+		Marriages:     [2][]santase.Suit{s.Marriages(p), s.Marriages(p.Other())},
+		Closed:        s.isClosed,
+		ClosedBy:      seat(s.closedBy),
+		ClosingScores: pair(s.closingScores),
+		ClosingTricks: pair(s.closingTricks),
+		Over:          s.isOver,
+		Winner:        seat(s.winner),
+		Claimed:       s.claimed,
+		ClaimedBy:     seat(s.claimedBy),
+		ClaimValid:    s.claimValid,
+	}
+}
+
+// PublicView returns what a spectator that sees neither hand can see of
+// the deal.
+func (s State) PublicView() View {
+	v := s.View(PlayerOne)
+	v.Hand = nil
+	v.HiddenHand = len(s.hands[PlayerOne])
+	return v
+}
+
+// ViewEvents returns the events as the player sees them, with the players
+// converted to their seats in the player's view. The cards drawn by the
+// opponent are not known to the player, so those events are left out.
+func ViewEvents(events []Event, p Player) []Event {
+	seat := func(q Player) Player {
+		if p == PlayerOne {
+			return q
+		}
+		return q.Other()
+	}
+
+	var result []Event
+	for _, e := range events {
+		switch e := e.(type) {
+		case TrumpSwitched:
+			e.Player = seat(e.Player)
+			result = append(result, e)
+		case GameClosed:
+			e.Player = seat(e.Player)
+			result = append(result, e)
+		case Announced:
+			e.Player = seat(e.Player)
+			result = append(result, e)
+		case CardPlayed:
+			e.Player = seat(e.Player)
+			result = append(result, e)
+		case TrickWon:
+			e.Player = seat(e.Player)
+			result = append(result, e)
+		case LastTrickBonusWon:
+			e.Player = seat(e.Player)
+			result = append(result, e)
+		case CardDrawn:
+			if e.Player == p {
+				e.Player = PlayerOne
+				result = append(result, e)
+			}
+		case Claimed:
+			e.Player = seat(e.Player)
+			result = append(result, e)
+		case GameOver:
+			e.Winner = seat(e.Winner)
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// PublicEvents returns the events as a spectator that sees neither hand
+// sees them, which is without the cards drawn.
+func PublicEvents(events []Event) []Event {
+	var result []Event
+	for _, e := range events {
+		if _, ok := e.(CardDrawn); !ok {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Hidden returns the cards the player has not seen, which are in the hand
+// of the opponent or in the stack, and in a public view also in the hand
+// of the player, sorted by suit and rank.
+func (v View) Hidden() []santase.Card {
+	seen := santase.NewPile()
+	for _, card := range v.Hand {
+		seen.AddCard(card)
+	}
+	for _, card := range v.Played {
+		seen.AddCard(card)
+	}
+	if v.TrumpCard != nil {
+		seen.AddCard(*v.TrumpCard)
+	}
+	if v.CardPlayed != nil {
+		seen.AddCard(*v.CardPlayed)
+	}
+
+	var hidden []santase.Card
+	for _, card := range santase.AllCards {
+		if !seen.HasCard(card) {
+			hidden = append(hidden, card)
+		}
+	}
+	return sortCards(hidden)
+}
+
+// Check returns an error if the numbers of the cards the player cannot
+// see do not add up to the cards returned by Hidden, in which case the
+// view cannot be made a state. Views decoded from JSON should be checked
+// before they are used.
+func (v View) Check() error {
+	if v.HiddenHand < 0 || v.OpponentCards < 0 || v.Stack < 0 {
+		return fmt.Errorf("negative number of hidden cards")
+	}
+	if hidden, counted := len(v.Hidden()), v.HiddenHand+v.OpponentCards+v.Stack; hidden != counted {
+		return fmt.Errorf("%d cards are hidden, but the hands and the stack hold %d", hidden, counted)
+	}
+	return nil
+}
+
+// State returns a state of the deal that looks to the player like the
+// real one. The cards the player cannot see are the passed hidden cards
+// in any order: in a public view the first HiddenHand of them are given
+// to the player, the next OpponentCards to the opponent and the rest form
+// the stack, its last card being the top one. The state can be shown and
+// it checks the moves of the player like the real one, but not those of
+// the opponent.
+//
+// Panics if the hidden cards are not the cards returned by Hidden or the
+// view does not pass Check.
+func (v View) State(hidden []santase.Card) State {
+	expected := v.Hidden()
+	if len(hidden) != len(expected) || len(hidden) != v.HiddenHand+v.OpponentCards+v.Stack {
+		panic("hidden cards do not match the view")
+	}
+	given := santase.NewPile()
+	for _, card := range hidden {
+		given.AddCard(card)
+	}
+	for _, card := range expected {
+		if !given.HasCard(card) {
+			panic("hidden cards do not match the view")
+		}
+	}
+
+	hand := v.Hand
+	if v.HiddenHand > 0 {
+		hand, hidden = hidden[:v.HiddenHand], hidden[v.HiddenHand:]
+	}
+	s := State{
+		rules:         v.Rules,
+		trump:         v.Trump,
+		trumpCard:     v.TrumpCard,
+		stack:         append([]santase.Card(nil), hidden[v.OpponentCards:]...),
+		hands:         [2]santase.Hand{santase.NewHand(hand...), santase.NewHand(hidden[:v.OpponentCards]...)},
+		scores:        v.Scores,
+		tricks:        v.Tricks,
+		pending:       v.Pending,
+		cardPlayed:    v.CardPlayed,
+		leader:        v.Leader,
+		toMove:        v.ToMove,
+		lastTrick:     v.LastTrick,
+		isClosed:      v.Closed,
+		closedBy:      v.ClosedBy,
+		isOver:        v.Over,
+		winner:        v.Winner,
+		claimed:       v.Claimed,
+		claimedBy:     v.ClaimedBy,
+		claimValid:    v.ClaimValid,
+		closingScores: v.ClosingScores,
+		closingTricks: v.ClosingTricks,
+		played:        append([]santase.Card(nil), v.Played...),
+	}
+	s.marriages[PlayerOne] = append([]santase.Suit(nil), v.Marriages[PlayerOne]...)
+	s.marriages[PlayerTwo] = append([]santase.Suit(nil), v.Marriages[PlayerTwo]...)
+	if s.trumpCard != nil {
+		card := *s.trumpCard
+		s.trumpCard = &card
+	}
+	if s.cardPlayed != nil {
+		card := *s.cardPlayed
+		s.cardPlayed = &card
+	}
+	return s
+}
+
+type jsonView struct {
+	Rules         jsonRules `json:"rules"`
+	Hand          string    `json:"hand"`
+	HiddenHand    int       `json:"hiddenHand,omitempty"`
+	OpponentCards int       `json:"opponentCards"`
+	Stack         int       `json:"stack"`
+	Played        string    `json:"played"`
+	Trump         string    `json:"trump"`
+	TrumpCard     string    `json:"trumpCard,omitempty"`
+	CardPlayed    string    `json:"cardPlayed,omitempty"`
+	Leader        int       `json:"leader"`
+	ToMove        int       `json:"toMove"`
+	LastTrick     int       `json:"lastTrick"`
+	Scores        [2]int    `json:"scores"`
+	Tricks        [2]int    `json:"tricks"`
+	Pending       [2]int    `json:"pending"`
+	Marriages     [2]string `json:"marriages"`
+	Closed        bool      `json:"closed"`
+	ClosedBy      int       `json:"closedBy,omitempty"`
+	ClosingScores [2]int    `json:"closingScores"`
+	ClosingTricks [2]int    `json:"closingTricks"`
+	Over          bool      `json:"over"`
+	Winner        int       `json:"winner,omitempty"`
+	Claimed       bool      `json:"claimed"`
+	ClaimedBy     int       `json:"claimedBy,omitempty"`
+	ClaimValid    bool      `json:"claimValid"`
+}
+
+// MarshalJSON encodes the view like a record: the cards are written in the
+// compact notation, the marriages as the letters of their suits and the
+// players are numbered from 1 (the player) to 2 (their opponent). Players
+// that do not apply, like the winner of a deal in progress, are left out.
+func (v View) MarshalJSON() ([]byte, error) {
+	marriages := func(suits []santase.Suit) string {
+		var b strings.Builder
+		for _, suit := range suits {
+			b.WriteString(FormatSuit(suit))
+		}
+		return b.String()
+	}
+	optionalCard := func(card *santase.Card) string {
+		if card == nil {
+			return ""
+		}
+		return FormatCard(*card)
+	}
+	optionalPlayer := func(set bool, p Player) int {
+		if !set {
+			return 0
+		}
+		return int(p) + 1
+	}
+
+	return json.Marshal(jsonView{
+		Rules:         jsonRules{AutoClaim: v.Rules.AutoClaim},
+		Hand:          FormatDeck(v.Hand),
+		HiddenHand:    v.HiddenHand,
+		OpponentCards: v.OpponentCards,
+		Stack:         v.Stack,
+		Played:        FormatDeck(v.Played),
+		Trump:         FormatSuit(v.Trump),
+		TrumpCard:     optionalCard(v.TrumpCard),
+		CardPlayed:    optionalCard(v.CardPlayed),
+		Leader:        int(v.Leader) + 1,
+		ToMove:        int(v.ToMove) + 1,
+		LastTrick:     int(v.LastTrick) + 1,
+		Scores:        v.Scores,
+		Tricks:        v.Tricks,
+		Pending:       v.Pending,
+		Marriages:     [2]string{marriages(v.Marriages[PlayerOne]), marriages(v.Marriages[PlayerTwo])},
+		Closed:        v.Closed,
+		ClosedBy:      optionalPlayer(v.Closed, v.ClosedBy),
+		ClosingScores: v.ClosingScores,
+		ClosingTricks: v.ClosingTricks,
+		Over:          v.Over,
+		Winner:        optionalPlayer(v.Over, v.Winner),
+		Claimed:       v.Claimed,
+		ClaimedBy:     optionalPlayer(v.Claimed, v.ClaimedBy),
+		ClaimValid:    v.ClaimValid,
+	})
+}
+
+// UnmarshalJSON decodes a view encoded with MarshalJSON.
+func (v *View) UnmarshalJSON(data []byte) error {
+	var in jsonView
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	var err error
+	card := func(s string) *santase.Card {
+		if s == "" || err != nil {
+			return nil
+		}
+		var c santase.Card
+		c, err = ParseCard(s)
+		return &c
+	}
+	cards := func(s string) []santase.Card {
+		if err != nil {
+			return nil
+		}
+		var result []santase.Card
+		result, err = ParseCards(s)
+		return result
+	}
+	suits := func(s string) []santase.Suit {
+		var result []santase.Suit
+		for _, letter := range s {
+			if err != nil {
+				return nil
+			}
+			var suit santase.Suit
+			suit, err = ParseSuit(string(letter))
+			result = append(result, suit)
+		}
+		return result
+	}
+	player := func(n int, optional bool) Player {
+		if (n != 1 && n != 2) && !(optional && n == 0) && err == nil {
+			err = fmt.Errorf("invalid player %d, expected 1 or 2", n)
+		}
+		if n == 0 {
+			return PlayerOne
+		}
+		return Player(n - 1)
+	}
+
+	trump, suitErr := ParseSuit(in.Trump)
+	if suitErr != nil {
+		return suitErr
+	}
+	out := View{
+		Rules:         Rules{AutoClaim: in.Rules.AutoClaim},
+		Hand:          cards(in.Hand),
+		HiddenHand:    in.HiddenHand,
+		OpponentCards: in.OpponentCards,
+		Stack:         in.Stack,
+		Played:        cards(in.Played),
+		Trump:         trump,
+		TrumpCard:     card(in.TrumpCard),
+		CardPlayed:    card(in.CardPlayed),
+		Leader:        player(in.Leader, false),
+		ToMove:        player(in.ToMove, false),
+		LastTrick:     player(in.LastTrick, false),
+		Scores:        in.Scores,
+		Tricks:        in.Tricks,
+		Pending:       in.Pending,
+		Marriages:     [2][]santase.Suit{suits(in.Marriages[0]), suits(in.Marriages[1])},
+		Closed:        in.Closed,
+		ClosedBy:      player(in.ClosedBy, true),
+		ClosingScores: in.ClosingScores,
+		ClosingTricks: in.ClosingTricks,
+		Over:          in.Over,
+		Winner:        player(in.Winner, true),
+		Claimed:       in.Claimed,
+		ClaimedBy:     player(in.ClaimedBy, true),
+		ClaimValid:    in.ClaimValid,
+	}
+	if err != nil {
+		return err
+	}
+	*v = out
+	return nil
+}
+
+// sortCards sorts the cards by suit and rank and returns them.
+func sortCards(cards []santase.Card) []santase.Card {
+	sort.Slice(cards, func(i, j int) bool {
+		return cards[i].Suit < cards[j].Suit || (cards[i].Suit == cards[j].Suit && cards[i].Rank < cards[j].Rank)
+	})
+	return cards
+}
@@ -0,0 +1,685 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/freetype/truetype"
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/ebitenutil"
+	"github.com/hajimehoshi/ebiten/inpututil"
+	"github.com/hajimehoshi/ebiten/text"
+	santase "github.com/nvlbg/santase-ai"
+	"golang.org/x/image/font"
+
+	cardAssets "github.com/nvlbg/santase-gui/assets/cards"
+	"github.com/nvlbg/santase-gui/assets/fonts"
+	"github.com/nvlbg/santase-gui/engine"
+	"github.com/nvlbg/santase-gui/network"
+	"github.com/nvlbg/santase-gui/table"
+)
+
+func createImageFromBytes(data []byte) *ebiten.Image {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := ebiten.NewImageFromImage(img, ebiten.FilterLinear)
+	if err != nil {
+		panic(err)
+	}
+
+	return result
+}
+
+type card struct {
+	card    *santase.Card
+	front   *ebiten.Image
+	back    *ebiten.Image
+	x       int
+	y       int
+	flipped bool
+	hidden  bool
+
+	// lift is how much the card is raised above its place
+	lift int
+
+	// dragged is whether the card follows the cursor instead of moving
+	// smoothly to its place
+	dragged bool
+
+	// focused is whether the card is selected with the keyboard
+	focused bool
+
+	// scale is the factor by which the images are scaled
+	scale float64
+
+	// pose is where the card is drawn in this frame, which differs from
+	// its place while it is moving
+	pose pose
+}
+
+// target returns the pose of the card when it is not moving.
+func (c *card) target() pose {
+	p := pose{x: float64(c.x), y: float64(c.y - c.lift), face: 1}
+	if c.flipped {
+		p.angle = math.Pi / 2
+	}
+	if c.hidden {
+		p.face = 0
+	}
+	return p
+}
+
+func (c *card) draw(screen *ebiten.Image) {
+	img := c.front
+	if c.pose.face < 0.5 {
+		img = c.back
+	}
+
+	if c.focused {
+		b := c.hitBox()
+		margin := 15 * c.scale
+		ebitenutil.DrawRect(screen, b.X-b.Width/2-margin, b.Y-b.Height/2-margin,
+			b.Width+2*margin, b.Height+2*margin, color.NRGBA{0xff, 0xff, 0x00, 0xff})
+	}
+
+	width, height := img.Size()
+	opts := ebiten.DrawImageOptions{}
+	opts.GeoM.Translate(-float64(width)/2, -float64(height)/2)
+	opts.GeoM.Scale(c.scale*math.Abs(2*c.pose.face-1), c.scale)
+	opts.GeoM.Rotate(c.pose.angle)
+	opts.GeoM.Translate(c.pose.x, c.pose.y)
+	screen.DrawImage(img, &opts)
+}
+
+// thinkingDelay is how long an agent has to think before it is shown that
+// it is thinking, so that the message does not flash for quick agents.
+const thinkingDelay = 200 * time.Millisecond
+
+// floatingTextFrames is the number of frames a floating text is shown.
+const floatingTextFrames = 120
+
+// floatingText is a short message, such as a bonus, that floats up from
+// its position and fades out.
+type floatingText struct {
+	text   string
+	x      int
+	y      int
+	frames int
+}
+
+// advance moves the text one frame forward and returns whether it should
+// still be shown.
+func (f *floatingText) advance() bool {
+	f.frames++
+	return f.frames < floatingTextFrames
+}
+
+func (f *floatingText) draw(screen *ebiten.Image, face font.Face) {
+	alpha := 0xff * (floatingTextFrames - f.frames) / floatingTextFrames
+	text.Draw(screen, f.text, face, f.x, f.y-f.frames/2, color.NRGBA{0xff, 0xff, 0x00, uint8(alpha)})
+}
+
+// resources holds the images and fonts shared by all deals and the layout
+// of the screen they are drawn on.
+type resources struct {
+	cards         map[santase.Card]*ebiten.Image
+	backCard      *ebiten.Image
+	font          *truetype.Font
+	fontFace      font.Face
+	fontFaceSmall font.Face
+	fontFaceBig   font.Face
+	layout        layout
+
+	// pointer follows the mouse across deals
+	pointer pointer
+}
+
+func loadResources() *resources {
+	cards := make(map[santase.Card]*ebiten.Image)
+
+	cards[santase.NewCard(santase.Nine, santase.Clubs)] = createImageFromBytes(cardAssets.Card9C)
+	cards[santase.NewCard(santase.Jack, santase.Clubs)] = createImageFromBytes(cardAssets.CardJC)
+	cards[santase.NewCard(santase.Queen, santase.Clubs)] = createImageFromBytes(cardAssets.CardQC)
+	cards[santase.NewCard(santase.King, santase.Clubs)] = createImageFromBytes(cardAssets.CardKC)
+	cards[santase.NewCard(santase.Ten, santase.Clubs)] = createImageFromBytes(cardAssets.Card10C)
+	cards[santase.NewCard(santase.Ace, santase.Clubs)] = createImageFromBytes(cardAssets.CardAC)
+
+	cards[santase.NewCard(santase.Nine, santase.Diamonds)] = createImageFromBytes(cardAssets.Card9D)
+	cards[santase.NewCard(santase.Jack, santase.Diamonds)] = createImageFromBytes(cardAssets.CardJD)
+	cards[santase.NewCard(santase.Queen, santase.Diamonds)] = createImageFromBytes(cardAssets.CardQD)
+	cards[santase.NewCard(santase.King, santase.Diamonds)] = createImageFromBytes(cardAssets.CardKD)
+	cards[santase.NewCard(santase.Ten, santase.Diamonds)] = createImageFromBytes(cardAssets.Card10D)
+	cards[santase.NewCard(santase.Ace, santase.Diamonds)] = createImageFromBytes(cardAssets.CardAD)
+
+	cards[santase.NewCard(santase.Nine, santase.Hearts)] = createImageFromBytes(cardAssets.Card9H)
+	cards[santase.NewCard(santase.Jack, santase.Hearts)] = createImageFromBytes(cardAssets.CardJH)
+	cards[santase.NewCard(santase.Queen, santase.Hearts)] = createImageFromBytes(cardAssets.CardQH)
+	cards[santase.NewCard(santase.King, santase.Hearts)] = createImageFromBytes(cardAssets.CardKH)
+	cards[santase.NewCard(santase.Ten, santase.Hearts)] = createImageFromBytes(cardAssets.Card10H)
+	cards[santase.NewCard(santase.Ace, santase.Hearts)] = createImageFromBytes(cardAssets.CardAH)
+
+	cards[santase.NewCard(santase.Nine, santase.Spades)] = createImageFromBytes(cardAssets.Card9S)
+	cards[santase.NewCard(santase.Jack, santase.Spades)] = createImageFromBytes(cardAssets.CardJS)
+	cards[santase.NewCard(santase.Queen, santase.Spades)] = createImageFromBytes(cardAssets.CardQS)
+	cards[santase.NewCard(santase.King, santase.Spades)] = createImageFromBytes(cardAssets.CardKS)
+	cards[santase.NewCard(santase.Ten, santase.Spades)] = createImageFromBytes(cardAssets.Card10S)
+	cards[santase.NewCard(santase.Ace, santase.Spades)] = createImageFromBytes(cardAssets.CardAS)
+
+	backCard := createImageFromBytes(cardAssets.CardBack)
+
+	font, err := truetype.Parse(fonts.ArcadeTTF)
+	if err != nil {
+		panic(err)
+	}
+
+	r := &resources{
+		cards:    cards,
+		backCard: backCard,
+		font:     font,
+	}
+	r.setLayout(newLayout(designWidth, designHeight))
+	return r
+}
+
+type game struct {
+	*resources
+	deal                *table.Deal
+	replay              bool
+	debugMode           bool
+	debugBtnPressedFlag bool
+	floatingText        *floatingText
+	animator            *animator
+	trickWinner         engine.Player
+
+	// hovered is the card the cursor was over in the last frame
+	hovered *santase.Card
+
+	// grab is the card the user holds with the mouse, if any
+	grab *grab
+
+	// focus is the index in the sorted hand of the user of the card
+	// selected with the keyboard; it is shown once the keyboard is used
+	focus     int
+	showFocus bool
+
+	// spectator is whether the user only watches the deal from the seat
+	// of PlayerOne; unless they see both hands in debug mode, the hand of
+	// PlayerOne is hidden from them as well
+	spectator bool
+}
+
+// grab is a card the user holds with the mouse. It is drawn at the same
+// offset from the cursor as where it was grabbed.
+type grab struct {
+	card santase.Card
+	dx   int
+	dy   int
+}
+
+// NewGame creates a game that shows a single deal.
+func NewGame(res *resources, deal *table.Deal) *game {
+	return &game{
+		resources: res,
+		deal:      deal,
+		animator:  newAnimator(1),
+	}
+}
+
+// NewRemoteGame creates a game for a deal played or watched over a
+// network.
+func NewRemoteGame(res *resources, remote *network.Deal) *game {
+	if remote.IsSpectated() {
+		return &game{
+			resources: res,
+			deal:      table.NewSpectator(remote.State(), remote),
+			animator:  newAnimator(1),
+			spectator: true,
+		}
+	}
+	return &game{
+		resources: res,
+		deal:      table.NewRemote(remote.State(), remote),
+		animator:  newAnimator(1),
+	}
+}
+
+// drawThinking shows that an agent is choosing its move and for how long
+// it has been thinking.
+func (g *game) drawThinking(screen *ebiten.Image) {
+	thinking, elapsed := g.deal.Thinking()
+	if !thinking || elapsed < thinkingDelay {
+		return
+	}
+
+	who, y := "Opponent", g.layout.top(240)
+	if g.spectator {
+		who = "Player two"
+	}
+	if g.deal.State().ToMove() == engine.PlayerOne {
+		who, y = "Your agent", g.layout.bottom(460)
+		if g.spectator {
+			who = "Player one"
+		}
+	}
+	dots := strings.Repeat(".", 1+int(elapsed/(300*time.Millisecond))%3)
+	message := fmt.Sprintf("%s is thinking%-3s %.1fs", who, dots, elapsed.Seconds())
+	text.Draw(screen, message, g.fontFaceSmall, g.layout.left(20), y, color.White)
+}
+
+func (g *game) getHand() []santase.Card {
+	return g.deal.SortedHand(engine.PlayerOne)
+}
+
+func (g *game) getOpponentHand() []santase.Card {
+	return g.deal.SortedHand(engine.PlayerTwo)
+}
+
+func (g *game) newCard(c *santase.Card, x, y int, flipped, hidden bool) *card {
+	return &card{
+		card:    c,
+		front:   g.cards[*c],
+		back:    g.backCard,
+		x:       x,
+		y:       y,
+		flipped: flipped,
+		hidden:  hidden && !g.debugMode,
+		scale:   g.layout.cardScale(),
+	}
+}
+
+// drawOrder returns the cards in the order they are drawn. Moving cards are
+// drawn above the others and the card the user drags above all of them.
+func (g *game) drawOrder(objects []*card) []*card {
+	ordered := make([]*card, 0, len(objects))
+	for _, obj := range objects {
+		if !g.animator.isMoving(obj) && !obj.dragged {
+			ordered = append(ordered, obj)
+		}
+	}
+	for _, obj := range objects {
+		if g.animator.isMoving(obj) && !obj.dragged {
+			ordered = append(ordered, obj)
+		}
+	}
+	for _, obj := range objects {
+		if obj.dragged {
+			ordered = append(ordered, obj)
+		}
+	}
+	return ordered
+}
+
+// play plays the move of the user. A move the deal rejects is logged and
+// ignored, so the user can make another one.
+func (g *game) play(move engine.Move) {
+	events, err := g.deal.Play(move)
+	if err != nil {
+		log.Printf("cannot play %s: %v", engine.FormatMove(move), err)
+		return
+	}
+	g.showEvents(events)
+}
+
+// isUserMove returns whether the user is on turn and can play.
+func (g *game) isUserMove() bool {
+	return !g.replay && g.deal.AwaitsUser() && g.deal.State().ToMove() == engine.PlayerOne
+}
+
+// actionButtons returns the buttons for the moves of the user, indexed by
+// their rows. A button is enabled if the user can make its move now.
+func (g *game) actionButtons() []*button {
+	isUserMove := g.isUserMove()
+	buttons := make([]*button, exchangeRow+1)
+	buttons[claimRow] = &button{label: "Claim 66", key: ebiten.KeyS,
+		enabled: isUserMove && g.deal.CanClaim()}
+	buttons[announceRow] = &button{label: "Announce", key: ebiten.KeyM,
+		enabled: isUserMove && g.deal.CanAnnounceAny(), active: g.deal.Announces()}
+	buttons[closeRow] = &button{label: "Close", key: ebiten.KeyC,
+		enabled: isUserMove && g.deal.CanDeclareClose(), active: g.deal.IsClosed()}
+	buttons[exchangeRow] = &button{label: "Exchange", key: ebiten.KeyT,
+		enabled: isUserMove && g.deal.CanDeclareSwitchTrumpCard(), active: g.deal.SwitchTrumpCard()}
+	for row, b := range buttons {
+		b.rect = g.layout.actionButton(row)
+	}
+	return buttons
+}
+
+// updateFocus moves the focus in the hand of the user with the left and
+// right arrow keys and returns the focused card, or nil if the focus is not
+// shown.
+func (g *game) updateFocus() *santase.Card {
+	hand := g.getHand()
+	if g.replay || g.deal.IsAgent(engine.PlayerOne) || len(hand) == 0 {
+		return nil
+	}
+
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyLeft):
+		g.focus--
+		g.showFocus = true
+	case inpututil.IsKeyJustPressed(ebiten.KeyRight):
+		g.focus++
+		g.showFocus = true
+	case g.pointer.JustPressed:
+		g.showFocus = false
+	}
+
+	if g.focus < 0 {
+		g.focus = 0
+	}
+	if g.focus >= len(hand) {
+		g.focus = len(hand) - 1
+	}
+	if !g.showFocus {
+		return nil
+	}
+	return &hand[g.focus]
+}
+
+// playCard plays the card from the hand of the user. A marriage is
+// announced with it only if the user has chosen to announce.
+func (g *game) playCard(c santase.Card) {
+	g.play(engine.Move{Card: c})
+}
+
+// showEvents shows what happened with the moves that have just been
+// played.
+func (g *game) showEvents(events []engine.Event) {
+	for _, e := range events {
+		switch e := e.(type) {
+		case engine.TrickWon:
+			g.trickWinner = e.Player
+		case engine.LastTrickBonusWon:
+			y := g.layout.bottom(520)
+			if e.Player == engine.PlayerTwo {
+				y = g.layout.top(230)
+			}
+			x := g.layout.centerX(600)
+			g.floatingText = &floatingText{text: "+" + strconv.Itoa(e.Points), x: x, y: y}
+		}
+	}
+}
+
+func (g *game) update(screen *ebiten.Image) error {
+	events, err := g.deal.Update()
+	if err != nil {
+		return err
+	}
+	for from, to := range g.deal.Revealed() {
+		g.animator.rename(from, to)
+	}
+	g.showEvents(events)
+
+	screen.Fill(color.NRGBA{0x00, 0xaa, 0x00, 0xff})
+	l := g.layout
+
+	// objects are in the order they are drawn, so later cards are above
+	// earlier ones
+	var objects []*card
+	cardX := 270
+	for _, card := range g.getHand() {
+		func(card santase.Card) {
+			objects = append(objects, g.newCard(&card, l.centerX(cardX), l.bottom(600), false, g.spectator))
+			cardX += 80
+		}(card)
+	}
+
+	cardX = 270
+	for _, card := range g.getOpponentHand() {
+		func(card santase.Card) {
+			objects = append(objects, g.newCard(&card, l.centerX(cardX), l.top(120), false, true))
+			cardX += 80
+		}(card)
+	}
+
+	state := g.deal.State()
+	trumpCard := g.deal.TrumpCard()
+	stack := state.Stack()
+	var trumpObj *card
+	if trumpCard != nil {
+		stackX, trumpX, y := l.left(84), l.left(120), l.middle(360)
+		if g.deal.IsClosed() {
+			trumpObj = g.newCard(trumpCard, trumpX, y, true, true)
+			objects = append(objects, g.newCard(&stack[len(stack)-1], stackX, y, false, true), trumpObj)
+		} else {
+			trumpObj = g.newCard(trumpCard, trumpX, y, true, false)
+			objects = append(objects, trumpObj, g.newCard(&stack[len(stack)-1], stackX, y, false, true))
+		}
+	}
+
+	cardPlayed, response, leader := g.deal.Table()
+	opponentPlayedFirst := leader == engine.PlayerTwo
+	if cardPlayed != nil {
+		var x, y int
+		if opponentPlayedFirst {
+			x, y = l.centerX(500), l.middle(340)
+		} else {
+			x, y = l.centerX(540), l.middle(360)
+		}
+		objects = append(objects, g.newCard(cardPlayed, x, y, false, false))
+	}
+
+	if response != nil {
+		var x, y int
+		if opponentPlayedFirst {
+			x, y = l.centerX(540), l.middle(360)
+		} else {
+			x, y = l.centerX(500), l.middle(340)
+		}
+		objects = append(objects, g.newCard(response, x, y, false, false))
+	}
+
+	// the hidden cards of a deal played over a network are not known,
+	// so there is nothing to show in debug mode
+	if !g.debugBtnPressedFlag && ebiten.IsKeyPressed(ebiten.KeyF12) && !g.deal.IsRemote() {
+		g.debugBtnPressedFlag = ebiten.IsKeyPressed(ebiten.KeyF12)
+		g.debugMode = !g.debugMode
+	}
+	g.debugBtnPressedFlag = ebiten.IsKeyPressed(ebiten.KeyF12)
+
+	p := &g.pointer
+	isUserMove := g.isUserMove()
+	hand := g.deal.Hand(engine.PlayerOne)
+
+	buttons := g.actionButtons()
+	switch {
+	case buttons[claimRow].pressed(p):
+		g.play(g.deal.ClaimMove())
+		isUserMove = false
+	case buttons[announceRow].pressed(p):
+		g.deal.ToggleAnnouncement()
+	case buttons[closeRow].pressed(p):
+		g.deal.DeclareClose()
+	case buttons[exchangeRow].pressed(p):
+		g.deal.DeclareSwitchTrumpCard()
+	}
+
+	playable := func(c santase.Card) bool {
+		return isUserMove && hand.HasCard(c) && g.deal.IsCardLegal(c)
+	}
+	nineTrump := santase.NewCard(santase.Nine, state.Trump())
+	grabbable := func(c santase.Card) bool {
+		canSwitch := c == nineTrump && isUserMove && g.deal.CanDeclareSwitchTrumpCard()
+		return playable(c) || canSwitch
+	}
+	if g.grab != nil && !grabbable(g.grab.card) {
+		g.grab = nil
+	}
+
+	focused := g.updateFocus()
+	if focused != nil && playable(*focused) &&
+		(inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeySpace)) {
+		g.playCard(*focused)
+		isUserMove = false
+	}
+
+	// the card the user drags follows the cursor and the card the cursor
+	// was over in the last frame is raised if it can be played
+	for _, obj := range objects {
+		obj.focused = focused != nil && *obj.card == *focused
+		switch {
+		case g.grab != nil && p.IsDragging():
+			if *obj.card == g.grab.card {
+				obj.x, obj.y = p.X+g.grab.dx, p.Y+g.grab.dy
+				obj.dragged = true
+			}
+		case (obj.focused || (g.hovered != nil && *obj.card == *g.hovered)) && playable(*obj.card):
+			obj.lift = l.size(20)
+		}
+	}
+
+	// the cards of a trick are swept towards the player that won it
+	sweepY := l.bottom(600)
+	if g.trickWinner == engine.PlayerTwo {
+		sweepY = l.top(120)
+	}
+	g.animator.animate(objects, l, sweepY)
+	objects = g.drawOrder(objects)
+
+	g.hovered = nil
+	if !g.deal.IsAgent(engine.PlayerOne) {
+		selected := cardAt(objects, p.X, p.Y)
+		if selected != nil && !p.IsDragging() {
+			g.hovered = selected.card
+		}
+
+		switch {
+		case p.JustPressed:
+			// a card is grabbed when the button is pressed over it and
+			// follows the cursor once it moves
+			if selected != nil && grabbable(*selected.card) {
+				g.grab = &grab{
+					card: *selected.card,
+					dx:   int(selected.pose.x) - p.X,
+					dy:   int(selected.pose.y) - p.Y,
+				}
+			}
+		case p.Dropped() && g.grab != nil:
+			// a card dropped anywhere else goes back to the hand
+			c := g.grab.card
+			if c == nineTrump && trumpObj != nil && trumpObj.hitBox().Contains(float64(p.X), float64(p.Y)) {
+				g.deal.DeclareSwitchTrumpCard()
+			} else if image.Pt(p.X, p.Y).In(l.tableArea()) && playable(c) {
+				g.playCard(c)
+			}
+		case p.Clicked() && selected != nil && isUserMove:
+			if playable(*selected.card) {
+				g.playCard(*selected.card)
+			} else if trumpCard != nil && *selected.card == *trumpCard {
+				g.deal.DeclareSwitchTrumpCard()
+			} else if len(stack) > 0 && *selected.card == stack[len(stack)-1] {
+				g.deal.DeclareClose()
+			}
+		}
+	}
+	if p.JustReleased {
+		g.grab = nil
+	}
+
+	floating := g.floatingText
+	if floating != nil && !floating.advance() {
+		g.floatingText = nil
+	}
+
+	if ebiten.IsDrawingSkipped() {
+		return nil
+	}
+
+	for _, obj := range objects {
+		obj.draw(screen)
+	}
+	g.animator.draw(screen)
+
+	g.drawScore(screen, engine.PlayerOne, l.bottom(680))
+
+	if trumpCard != nil {
+		text.Draw(screen, strconv.Itoa(1+len(stack))+" cards", g.fontFaceSmall, l.left(20), l.middle(490), color.White)
+	}
+
+	if g.debugMode || g.spectator {
+		g.drawScore(screen, engine.PlayerTwo, l.top(250))
+	}
+
+	if announcement := g.deal.Announcement(); announcement != 0 {
+		var x, y int
+		if state.ToMove() == engine.PlayerTwo {
+			x, y = l.centerX(650), l.middle(450)
+		} else {
+			x, y = l.centerX(275), l.middle(300)
+		}
+		text.Draw(screen, strconv.Itoa(announcement), g.fontFaceBig, x, y, color.NRGBA{0xff, 0x00, 0x00, 0xff})
+	}
+
+	g.drawThinking(screen)
+
+	if floating != nil {
+		floating.draw(screen, g.fontFaceBig)
+	}
+
+	g.drawMarriages(screen, engine.PlayerOne, l.bottom(560))
+	g.drawMarriages(screen, engine.PlayerTwo, l.top(110))
+
+	// the buttons show the moves the user can make after this frame
+	if !g.replay && !g.spectator {
+		for _, b := range g.actionButtons() {
+			b.draw(screen, g.resources)
+		}
+	}
+
+	return nil
+}
+
+// drawScore shows the score of the player followed by the dimmed points of
+// the marriages they have announced before taking a trick, if any. The
+// text is aligned to the right edge of the screen.
+func (g *game) drawScore(screen *ebiten.Image, p engine.Player, y int) {
+	state := g.deal.State()
+	l := g.layout
+	score := "Score:" + strconv.Itoa(state.Score(p))
+	pending := ""
+	if points := state.PendingScore(p); points != 0 {
+		pending = " +" + strconv.Itoa(points)
+	}
+
+	x := l.right(936) - len(score+pending)*l.size(fontSize)
+	text.Draw(screen, score, g.fontFace, x, y, color.White)
+	if pending != "" {
+		x += len(score) * l.size(fontSize)
+		text.Draw(screen, pending, g.fontFace, x, y, color.NRGBA{0xff, 0xff, 0xff, 0x60})
+	}
+}
+
+// drawMarriages lists the marriages the player has announced in this deal
+// next to their hand, one per line starting at y.
+func (g *game) drawMarriages(screen *ebiten.Image, p engine.Player, y int) {
+	state := g.deal.State()
+	marriages := state.Marriages(p)
+	if len(marriages) == 0 {
+		return
+	}
+
+	l := g.layout
+	text.Draw(screen, "Marriages", g.fontFaceSmall, l.left(20), y, color.White)
+	for i, suit := range marriages {
+		points := 20
+		if suit == state.Trump() {
+			points = 40
+		}
+		line := fmt.Sprintf("%d %s", points, engine.FormatSuit(suit))
+		text.Draw(screen, line, g.fontFaceSmall, l.left(20), y+l.size(20*(i+1)), color.White)
+	}
+}
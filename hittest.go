@@ -0,0 +1,30 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import "github.com/nvlbg/santase-gui/hittest"
+
+// hitBox returns the area the card covers in the pose it is drawn in,
+// using the same transform as draw.
+func (c *card) hitBox() hittest.Box {
+	img := c.front
+	if c.pose.face < 0.5 {
+		img = c.back
+	}
+	width, height := img.Size()
+	return hittest.Card(c.pose.x, c.pose.y, c.pose.angle, c.pose.face, width, height, c.scale, float64(c.lift))
+}
+
+// cardAt returns the topmost of the cards, given in the order they are
+// drawn, that covers the point, or nil if there is none.
+func cardAt(cards []*card, x, y int) *card {
+	boxes := make([]hittest.Box, len(cards))
+	for i, c := range cards {
+		boxes[i] = c.hitBox()
+	}
+	if i := hittest.Topmost(boxes, x, y); i >= 0 {
+		return cards[i]
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Package hittest finds the card under the cursor. It only knows the
+// geometry the cards are drawn with and not the images, so it can be used
+// and tested without a display.
+package hittest
+
+import "math"
+
+// Box is the area a card covers on the screen: a rectangle of the passed
+// size centered at X, Y and rotated by Angle around its center.
+type Box struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+	Angle  float64
+
+	// Below extends the rectangle downwards before it is rotated, so that
+	// a raised card still covers the place it was raised from
+	Below float64
+}
+
+// Card returns the box of a card image of the passed size drawn scaled by
+// scale, centered at x, y and rotated by angle. face is 1 if the face of
+// the card is shown, 0 if its back and in between while the card is being
+// flipped, which narrows the card as it is drawn. below is how much the
+// card is raised above its place.
+func Card(x, y, angle, face float64, width, height int, scale, below float64) Box {
+	return Box{
+		X:      x,
+		Y:      y,
+		Width:  float64(width) * scale * math.Abs(2*face-1),
+		Height: float64(height) * scale,
+		Angle:  angle,
+		Below:  below,
+	}
+}
+
+// Contains returns whether the point is inside the box. The point is
+// rotated back around the center of the box and compared with the
+// rectangle before rotation.
+func (b Box) Contains(x, y float64) bool {
+	sin, cos := math.Sincos(-b.Angle)
+	dx, dy := x-b.X, y-b.Y
+	localX := dx*cos - dy*sin
+	localY := dx*sin + dy*cos
+	return math.Abs(localX) <= b.Width/2 && localY >= -b.Height/2 && localY <= b.Height/2+b.Below
+}
+
+// Topmost returns the index of the topmost of the boxes, given in the
+// order they are drawn, that contains the point, or -1 if there is none.
+func Topmost(boxes []Box, x, y int) int {
+	for i := len(boxes) - 1; i >= 0; i-- {
+		if boxes[i].Contains(float64(x), float64(y)) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,91 @@
+package hittest
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBoxContains(t *testing.T) {
+	tests := []struct {
+		name string
+		box  Box
+		x, y float64
+		want bool
+	}{
+		{"center", Box{X: 100, Y: 100, Width: 20, Height: 40}, 100, 100, true},
+		{"left edge", Box{X: 100, Y: 100, Width: 20, Height: 40}, 90, 100, true},
+		{"left of the box", Box{X: 100, Y: 100, Width: 20, Height: 40}, 89, 100, false},
+		{"top edge", Box{X: 100, Y: 100, Width: 20, Height: 40}, 100, 80, true},
+		{"below the box", Box{X: 100, Y: 100, Width: 20, Height: 40}, 100, 121, false},
+		{"raised above its place", Box{X: 100, Y: 100, Width: 20, Height: 40, Below: 10}, 100, 129, true},
+		{"below its place", Box{X: 100, Y: 100, Width: 20, Height: 40, Below: 10}, 100, 131, false},
+		{"rotated, along its length", Box{X: 100, Y: 100, Width: 20, Height: 40, Angle: math.Pi / 2}, 118, 100, true},
+		{"rotated, across its width", Box{X: 100, Y: 100, Width: 20, Height: 40, Angle: math.Pi / 2}, 100, 118, false},
+		{"tilted, along its length", Box{X: 100, Y: 100, Width: 20, Height: 40, Angle: math.Pi / 4}, 87, 113, true},
+		{"tilted, across its width", Box{X: 100, Y: 100, Width: 20, Height: 40, Angle: math.Pi / 4}, 113, 113, false},
+		{"flipped edge on", Box{X: 100, Y: 100, Width: 0, Height: 40}, 101, 100, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.box.Contains(test.x, test.y); got != test.want {
+				t.Errorf("%+v contains (%v, %v) = %v, want %v", test.box, test.x, test.y, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCard(t *testing.T) {
+	tests := []struct {
+		name  string
+		face  float64
+		scale float64
+		want  Box
+	}{
+		{"face up", 1, 1, Box{X: 10, Y: 20, Width: 20, Height: 40, Angle: 0.5, Below: 5}},
+		{"face down", 0, 1, Box{X: 10, Y: 20, Width: 20, Height: 40, Angle: 0.5, Below: 5}},
+		{"being flipped", 0.25, 1, Box{X: 10, Y: 20, Width: 10, Height: 40, Angle: 0.5, Below: 5}},
+		{"edge on", 0.5, 1, Box{X: 10, Y: 20, Width: 0, Height: 40, Angle: 0.5, Below: 5}},
+		{"scaled", 1, 1.5, Box{X: 10, Y: 20, Width: 30, Height: 60, Angle: 0.5, Below: 5}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Card(10, 20, 0.5, test.face, 20, 40, test.scale, 5); got != test.want {
+				t.Errorf("Card() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTopmost(t *testing.T) {
+	boxes := []Box{
+		Card(100, 100, 0, 1, 20, 40, 1, 0),
+		Card(110, 100, 0, 1, 20, 40, 1, 0),
+		Card(200, 100, math.Pi/2, 1, 20, 40, 1, 0),
+		Card(300, 100, 0, 0.25, 20, 40, 1, 0),
+	}
+
+	tests := []struct {
+		name string
+		x, y int
+		want int
+	}{
+		{"only the bottom card", 92, 100, 0},
+		{"overlapping cards", 105, 100, 1},
+		{"only the top card", 118, 100, 1},
+		{"nothing", 150, 100, -1},
+		{"rotated card", 218, 100, 2},
+		{"beside the rotated card", 200, 118, -1},
+		{"card being flipped", 304, 100, 3},
+		{"beside the card being flipped", 306, 100, -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Topmost(boxes, test.x, test.y); got != test.want {
+				t.Errorf("Topmost(%d, %d) = %d, want %d", test.x, test.y, got, test.want)
+			}
+		})
+	}
+}
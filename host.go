@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/nvlbg/santase-gui/engine"
+	"github.com/nvlbg/santase-gui/network"
+)
+
+// host runs the host subcommand which holds a match between two players
+// that connect to it with --connect.
+func host(args []string) {
+	flags := flag.NewFlagSet("host", flag.ExitOnError)
+	listen := flags.String("listen", fmt.Sprintf(":%d", network.DefaultPort), "address to listen on for the players")
+	seed := flags.Int64("seed", 0, "seed for shuffling the cards; a random seed is used if 0")
+	autoClaim := flags.Bool("auto-claim", false, "end the deal as soon as a player collects 66 points")
+	spectateHands := flags.Bool("spectate-hands", false, "let spectators see both hands; the players can connect as spectators too")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of %s host:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("waiting for players on %v (seed %d)", l.Addr(), *seed)
+
+	server := network.NewServer(engine.Rules{AutoClaim: *autoClaim}, *seed)
+	if *spectateHands {
+		server.AllowHands()
+	}
+	if err := server.Serve(l); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/inpututil"
+
+	"github.com/nvlbg/santase-gui/mouse"
+)
+
+// dragDistance is how far the cursor has to move on a screen of the design
+// size while the mouse button is held for the press to become a drag.
+const dragDistance = 6
+
+// pointer follows the left mouse button of ebiten and tells a click from a
+// drag.
+type pointer struct {
+	mouse.Pointer
+}
+
+// update reads the state of the mouse in this frame. It must be called
+// once at the start of every frame.
+func (p *pointer) update(l layout) {
+	x, y := ebiten.CursorPosition()
+	p.Update(x, y, inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft),
+		inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft), l.size(dragDistance))
+}
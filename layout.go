@@ -0,0 +1,197 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"errors"
+	"image"
+	"math"
+
+	"github.com/golang/freetype/truetype"
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/inpututil"
+)
+
+// designWidth and designHeight are the size of the screen for which the
+// positions of everything on the table are given. On a screen of another
+// size the positions are scaled and kept at the same distance from the
+// edge or the center of the screen they are measured from.
+const (
+	designWidth  = 960
+	designHeight = 720
+)
+
+// cardScale is the size of the cards relative to their images on a screen
+// of the design size.
+const cardScale = 0.2
+
+// Sizes of the fonts on a screen of the design size.
+const (
+	fontSize      = 22
+	fontSizeSmall = 16
+	fontSizeBig   = 50
+)
+
+// layout converts positions on the design screen to positions on the
+// actual screen.
+type layout struct {
+	width  int
+	height int
+	scale  float64
+}
+
+func newLayout(width, height int) layout {
+	scale := math.Min(float64(width)/designWidth, float64(height)/designHeight)
+	return layout{width, height, scale}
+}
+
+// size scales a length.
+func (l layout) size(v int) int {
+	return int(math.Round(float64(v) * l.scale))
+}
+
+// left, right and centerX convert an x coordinate measured from the left
+// edge, the right edge or the center of the screen respectively.
+func (l layout) left(x int) int {
+	return l.size(x)
+}
+
+func (l layout) right(x int) int {
+	return l.width - l.size(designWidth-x)
+}
+
+func (l layout) centerX(x int) int {
+	return l.width/2 + l.size(x-designWidth/2)
+}
+
+// top, bottom and middle convert a y coordinate measured from the top
+// edge, the bottom edge or the middle of the screen respectively.
+func (l layout) top(y int) int {
+	return l.size(y)
+}
+
+func (l layout) bottom(y int) int {
+	return l.height - l.size(designHeight-y)
+}
+
+func (l layout) middle(y int) int {
+	return l.height/2 + l.size(y-designHeight/2)
+}
+
+// centerText returns the x coordinate at which a text written with the
+// arcade font of the passed size is centered on the screen. The glyphs of
+// the font are as wide as its size.
+func (l layout) centerText(s string, size int) int {
+	return l.width/2 - len(s)*l.size(size)/2
+}
+
+// cardScale returns the factor by which the card images are scaled.
+func (l layout) cardScale() float64 {
+	return cardScale * l.scale
+}
+
+// tableArea returns the area of the table onto which the user drops a card
+// to play it.
+func (l layout) tableArea() image.Rectangle {
+	return image.Rect(l.centerX(340), l.middle(230), l.centerX(700), l.middle(470))
+}
+
+// Rows of the buttons for the moves of the user, counted from the bottom,
+// and of the buttons of the menu, counted from the top.
+const (
+	claimRow = iota
+	announceRow
+	closeRow
+	exchangeRow
+)
+
+const (
+	saveRow = iota
+	replayRow
+	newGameRow
+	quitRow
+)
+
+// actionButton returns the area of the button in the row of the column of
+// buttons for the moves of the user at the right of the screen.
+func (l layout) actionButton(row int) image.Rectangle {
+	y := 520 - row*50
+	return image.Rect(l.right(770), l.bottom(y), l.right(930), l.bottom(y+40))
+}
+
+// menuButton returns the area of the button in the row of the menu at the
+// top right corner of the screen.
+func (l layout) menuButton(row int) image.Rectangle {
+	y := 20 + row*50
+	return image.Rect(l.right(770), l.top(y), l.right(930), l.top(y+40))
+}
+
+// resize updates the layout and the fonts to the size of the screen. It is
+// called at the start of every frame.
+func (r *resources) resize(screen *ebiten.Image) {
+	width, height := screen.Size()
+	if r.layout.width != width || r.layout.height != height {
+		r.setLayout(newLayout(width, height))
+	}
+}
+
+func (r *resources) setLayout(l layout) {
+	r.layout = l
+	r.fontFace = truetype.NewFace(r.font, &truetype.Options{Size: fontSize * l.scale})
+	r.fontFaceSmall = truetype.NewFace(r.font, &truetype.Options{Size: fontSizeSmall * l.scale})
+	r.fontFaceBig = truetype.NewFace(r.font, &truetype.Options{Size: fontSizeBig * l.scale})
+}
+
+// window keeps track of the size of the window and switches between the
+// window and fullscreen. The screen has as many pixels as the window has
+// on the display, so that on HiDPI displays nothing is blurred.
+type window struct {
+	width  int
+	height int
+}
+
+// errQuit ends the game loop when the user quits.
+var errQuit = errors.New("quit")
+
+// screenSize returns the size of the screen for a window of the passed
+// size in device independent pixels and the scale to pass to ebiten.
+func screenSize(width, height int) (int, int, float64) {
+	factor := ebiten.DeviceScaleFactor()
+	return int(float64(width) * factor), int(float64(height) * factor), 1 / factor
+}
+
+// run opens the window and calls update every frame until the user quits
+// by pressing Q.
+func (w *window) run(update func(*ebiten.Image) error, title string) error {
+	width, height, scale := screenSize(w.width, w.height)
+	err := ebiten.Run(func(screen *ebiten.Image) error {
+		if inpututil.IsKeyJustPressed(ebiten.KeyQ) {
+			return errQuit
+		}
+		w.toggleFullscreen()
+		return update(screen)
+	}, width, height, scale, title)
+	if err == errQuit {
+		return nil
+	}
+	return err
+}
+
+// toggleFullscreen switches between the window and fullscreen when F11 is
+// pressed.
+func (w *window) toggleFullscreen() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF11) {
+		fullscreen := !ebiten.IsFullscreen()
+		var width, height int
+		var scale float64
+		if fullscreen {
+			width, height, scale = screenSize(ebiten.ScreenSizeInFullscreen())
+		} else {
+			width, height, scale = screenSize(w.width, w.height)
+		}
+		ebiten.SetFullscreen(fullscreen)
+		ebiten.SetScreenSize(width, height)
+		ebiten.SetScreenScale(scale)
+	}
+}
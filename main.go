@@ -1,681 +1,252 @@
 package main
 
 import (
-	"bytes"
+	"encoding/json"
+	"flag"
 	"fmt"
-	"image"
-	"image/color"
-	_ "image/png"
-	"math/rand"
-	"sort"
-	"strconv"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
 	"time"
 
-	"github.com/golang/freetype/truetype"
-	"github.com/hajimehoshi/ebiten"
-	"github.com/hajimehoshi/ebiten/text"
 	santase "github.com/nvlbg/santase-ai"
-	"github.com/nvlbg/santase-ai/agents/ismcts"
-	"golang.org/x/image/font"
 
-	// "github.com/nvlbg/santase-ai/agents/random"
-
-	cardAssets "github.com/nvlbg/santase-gui/assets/cards"
-	"github.com/nvlbg/santase-gui/assets/fonts"
+	"github.com/nvlbg/santase-gui/agents"
+	"github.com/nvlbg/santase-gui/engine"
+	"github.com/nvlbg/santase-gui/network"
+	"github.com/nvlbg/santase-gui/table"
 )
 
-func createImageFromBytes(data []byte) *ebiten.Image {
-	img, _, err := image.Decode(bytes.NewReader(data))
-	if err != nil {
-		panic(err)
-	}
-
-	result, err := ebiten.NewImageFromImage(img, ebiten.FilterLinear)
-	if err != nil {
-		panic(err)
-	}
-
-	return result
-}
-
-type card struct {
-	card    *santase.Card
-	rect    image.Rectangle
-	image   *ebiten.Image
-	x       int
-	y       int
-	zIndex  int
-	flipped bool
-}
-
-func (c *card) draw(screen *ebiten.Image) {
-	width, height := c.image.Size()
-	opts := ebiten.DrawImageOptions{}
-	opts.GeoM.Translate(-float64(width)/2, -float64(height)/2)
-	opts.GeoM.Scale(0.2, 0.2)
-	if c.flipped {
-		opts.GeoM.Rotate(1.570796)
-	}
-	opts.GeoM.Translate(float64(c.x), float64(c.y))
-	screen.DrawImage(c.image, &opts)
-}
-
-func (c *card) intersects(x, y int) bool {
-	return x >= c.rect.Min.X && x <= c.rect.Max.X && y >= c.rect.Min.Y && y <= c.rect.Max.Y
-}
-
-type game struct {
-	score               int
-	opponentScore       int
-	isOver              bool
-	isClosed            bool
-	opponentClosedGame  bool
-	trump               santase.Suit
-	hand                santase.Hand
-	opponentHand        santase.Hand
-	trumpCard           *santase.Card
-	stack               []santase.Card
-	cardPlayed          *santase.Card
-	response            *santase.Card
-	opponentPlayedFirst bool
-	isOpponentMove      bool
-	blockUI             bool
-	switchTrumpCard     bool
-	closeGame           bool
-	cards               map[santase.Card]*ebiten.Image
-	backCard            *ebiten.Image
-	userMoves           chan santase.Move
-	opponentAI          santase.Game
-	playerAI            *santase.Game
-	fontFace            font.Face
-	fontFaceSmall       font.Face
-	fontFaceBig         font.Face
-	debugMode           bool
-	debugBtnPressedFlag bool
-	announcement        int
-}
-
-func NewGame(opponentAgent santase.Agent, playerAgent *santase.Agent) game {
-	cards := make(map[santase.Card]*ebiten.Image)
-
-	cards[santase.NewCard(santase.Nine, santase.Clubs)] = createImageFromBytes(cardAssets.Card9C)
-	cards[santase.NewCard(santase.Jack, santase.Clubs)] = createImageFromBytes(cardAssets.CardJC)
-	cards[santase.NewCard(santase.Queen, santase.Clubs)] = createImageFromBytes(cardAssets.CardQC)
-	cards[santase.NewCard(santase.King, santase.Clubs)] = createImageFromBytes(cardAssets.CardKC)
-	cards[santase.NewCard(santase.Ten, santase.Clubs)] = createImageFromBytes(cardAssets.Card10C)
-	cards[santase.NewCard(santase.Ace, santase.Clubs)] = createImageFromBytes(cardAssets.CardAC)
+// settings holds the options the game was started with.
+type settings struct {
+	rules engine.Rules
 
-	cards[santase.NewCard(santase.Nine, santase.Diamonds)] = createImageFromBytes(cardAssets.Card9D)
-	cards[santase.NewCard(santase.Jack, santase.Diamonds)] = createImageFromBytes(cardAssets.CardJD)
-	cards[santase.NewCard(santase.Queen, santase.Diamonds)] = createImageFromBytes(cardAssets.CardQD)
-	cards[santase.NewCard(santase.King, santase.Diamonds)] = createImageFromBytes(cardAssets.CardKD)
-	cards[santase.NewCard(santase.Ten, santase.Diamonds)] = createImageFromBytes(cardAssets.Card10D)
-	cards[santase.NewCard(santase.Ace, santase.Diamonds)] = createImageFromBytes(cardAssets.CardAD)
+	// seed is used to shuffle the cards for the deals of the match
+	seed int64
 
-	cards[santase.NewCard(santase.Nine, santase.Hearts)] = createImageFromBytes(cardAssets.Card9H)
-	cards[santase.NewCard(santase.Jack, santase.Hearts)] = createImageFromBytes(cardAssets.CardJH)
-	cards[santase.NewCard(santase.Queen, santase.Hearts)] = createImageFromBytes(cardAssets.CardQH)
-	cards[santase.NewCard(santase.King, santase.Hearts)] = createImageFromBytes(cardAssets.CardKH)
-	cards[santase.NewCard(santase.Ten, santase.Hearts)] = createImageFromBytes(cardAssets.Card10H)
-	cards[santase.NewCard(santase.Ace, santase.Hearts)] = createImageFromBytes(cardAssets.CardAH)
+	// deal is the deck of the first deal; if nil it is shuffled using
+	// the seed like the decks of the other deals
+	deal []santase.Card
 
-	cards[santase.NewCard(santase.Nine, santase.Spades)] = createImageFromBytes(cardAssets.Card9S)
-	cards[santase.NewCard(santase.Jack, santase.Spades)] = createImageFromBytes(cardAssets.CardJS)
-	cards[santase.NewCard(santase.Queen, santase.Spades)] = createImageFromBytes(cardAssets.CardQS)
-	cards[santase.NewCard(santase.King, santase.Spades)] = createImageFromBytes(cardAssets.CardKS)
-	cards[santase.NewCard(santase.Ten, santase.Spades)] = createImageFromBytes(cardAssets.Card10S)
-	cards[santase.NewCard(santase.Ace, santase.Spades)] = createImageFromBytes(cardAssets.CardAS)
+	// record is a saved deal which is continued as the first deal
+	record *engine.Record
 
-	backCard := createImageFromBytes(cardAssets.CardBack)
+	// recordsDir is the directory the deals are saved to
+	recordsDir string
 
-	allCards := make([]santase.Card, 0, 24)
-	for _, card := range santase.AllCards {
-		allCards = append(allCards, card)
-	}
-
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-
-	// you can seed your random number generator like so
-	// this way the same game will be repeated between runs
-	// rng := rand.New(rand.NewSource(42))
+	// animationSpeed multiplies the speed of the card animations; 0
+	// turns them off
+	animationSpeed float64
 
-	rng.Shuffle(len(allCards), func(i, j int) {
-		allCards[i], allCards[j] = allCards[j], allCards[i]
-	})
+	// width and height are the size of the window
+	width  int
+	height int
 
-	hand := santase.NewHand(allCards[:6]...)
-	opponentHand := santase.NewHand(allCards[6:12]...)
-	trumpCard := &allCards[12]
-	isOpponentMove := false
-	opponentAI := santase.CreateGame(opponentHand.Clone(), *trumpCard, !isOpponentMove)
-	opponentAI.SetAgent(opponentAgent)
-
-	var playerAI *santase.Game
-	if playerAgent != nil {
-		ai := santase.CreateGame(hand.Clone(), *trumpCard, isOpponentMove)
-		playerAI = &ai
-		playerAI.SetAgent(*playerAgent)
-	}
+	// connect is the address of the server of a match played over a
+	// network, if any
+	connect string
 
-	font, err := truetype.Parse(fonts.ArcadeTTF)
-	if err != nil {
-		panic(err)
-	}
-	face := truetype.NewFace(font, &truetype.Options{Size: 22})
-	smallFace := truetype.NewFace(font, &truetype.Options{Size: 16})
-	bigFace := truetype.NewFace(font, &truetype.Options{Size: 50})
+	// spectate is the spectator mode in which the match on the server is
+	// watched, or empty if the user plays in it
+	spectate string
 
-	return game{
-		score:               0,
-		opponentScore:       0,
-		isOver:              false,
-		isClosed:            false,
-		opponentClosedGame:  false,
-		trump:               allCards[12].Suit,
-		hand:                hand,
-		opponentHand:        opponentHand,
-		trumpCard:           trumpCard,
-		stack:               allCards[13:],
-		cardPlayed:          nil,
-		response:            nil,
-		isOpponentMove:      isOpponentMove,
-		blockUI:             false,
-		switchTrumpCard:     false,
-		closeGame:           false,
-		cards:               cards,
-		backCard:            backCard,
-		userMoves:           make(chan santase.Move),
-		opponentAI:          opponentAI,
-		playerAI:            playerAI,
-		fontFace:            face,
-		fontFaceSmall:       smallFace,
-		fontFaceBig:         bigFace,
-		debugMode:           false,
-		debugBtnPressedFlag: false,
-		announcement:        0,
-	}
+	// spectators are shown the deals of a local match, if not nil
+	spectators *network.Broadcast
 }
 
-func (g *game) getHand() []santase.Card {
-	cards := g.hand.ToSlice()
-	sort.Slice(cards, func(i, j int) bool {
-		return cards[i].Suit < cards[j].Suit || (cards[i].Suit == cards[j].Suit && cards[i].Rank < cards[j].Rank)
-	})
-	return cards
-}
-
-func (g *game) getOpponentHand() []santase.Card {
-	cards := g.opponentHand.ToSlice()
-	sort.Slice(cards, func(i, j int) bool {
-		return cards[i].Suit < cards[j].Suit || (cards[i].Suit == cards[j].Suit && cards[i].Rank < cards[j].Rank)
-	})
-	return cards
-}
-
-func (g *game) newCard(c *santase.Card, x, y, z int, flipped bool) *card {
-	var img *ebiten.Image
-	if !g.hand.HasCard(*c) && c != g.trumpCard && c != g.cardPlayed && c != g.response && !g.debugMode {
-		img = g.backCard
-	} else if g.isClosed && c == g.trumpCard && !g.debugMode {
-		img = g.backCard
-	} else {
-		img = g.cards[*c]
-	}
-
-	width, height := img.Size()
-
-	if flipped {
-		width, height = height, width
-	}
-
-	width /= 5
-	height /= 5
-
-	return &card{
-		card:    c,
-		rect:    image.Rect(x-width/2, y-width/2, x+width/2, y+height/2),
-		image:   img,
-		x:       x,
-		y:       y,
-		zIndex:  z,
-		flipped: flipped,
-	}
-}
-
-func (g *game) drawCard() santase.Card {
-	if len(g.stack) > 0 {
-		result := g.stack[len(g.stack)-1]
-		g.stack = g.stack[:len(g.stack)-1]
-		return result
-	}
-
-	if g.trumpCard != nil {
-		result := *g.trumpCard
-		g.trumpCard = nil
-		return result
-	}
-
-	panic("all cards have been drawn")
-}
-
-func (g *game) drawCards(opponentWon bool) {
-	if g.trumpCard != nil && !g.isClosed && len(g.hand) == 5 && len(g.opponentHand) == 5 {
-		var opponentDrawnCard, playerDrawnCard santase.Card
-		if opponentWon {
-			opponentDrawnCard, playerDrawnCard = g.drawCard(), g.drawCard()
-		} else {
-			playerDrawnCard, opponentDrawnCard = g.drawCard(), g.drawCard()
-		}
-		g.opponentAI.UpdateDrawnCard(opponentDrawnCard)
-		if g.playerAI != nil {
-			g.playerAI.UpdateDrawnCard(playerDrawnCard)
-		}
-		g.hand.AddCard(playerDrawnCard)
-		g.opponentHand.AddCard(opponentDrawnCard)
-	}
-}
-
-func (g *game) playResponse(card *santase.Card) {
-	g.blockUI = true
-	g.response = card
-	<-time.After(2 * time.Second)
-
-	stronger := g.opponentAI.StrongerCard(g.cardPlayed, g.response)
-	opponentWon := (g.opponentPlayedFirst && stronger == g.cardPlayed) ||
-		(!g.opponentPlayedFirst && stronger == g.response)
-
-	handPoints := santase.Points(g.cardPlayed) + santase.Points(g.response)
-	g.cardPlayed = nil
-	g.response = nil
-
-	if opponentWon {
-		g.opponentScore += handPoints
-	} else {
-		g.score += handPoints
-	}
-
-	if g.score >= 66 || g.opponentScore >= 66 ||
-		((g.isClosed || len(g.stack) == 0) && len(g.hand) == 0) {
-		g.isOver = true
-	} else {
-		g.drawCards(opponentWon)
-
-		if opponentWon {
-			g.isOpponentMove = true
-			g.playAIMove(true)
-		} else {
-			g.isOpponentMove = false
-			if g.playerAI != nil {
-				g.playAIMove(false)
-			}
-		}
-
-		g.blockUI = false
-	}
-}
-
-func (g *game) isCardLegal(card santase.Card) bool {
-	// you're first to play or the game is not closed
-	if g.cardPlayed == nil || (g.trumpCard != nil && !g.isClosed) {
-		return true
-	}
-
-	// playing stronger card of the requested suit
-	if card.Suit == g.cardPlayed.Suit && card.Rank > g.cardPlayed.Rank {
-		return true
-	}
-
-	if g.cardPlayed.Suit == card.Suit {
-		for c := range g.hand {
-			if c.Suit == g.cardPlayed.Suit && c.Rank > g.cardPlayed.Rank {
-				// you're holding stronger card of the same suit that you must play
-				return false
-			}
-		}
-		// you don't have stronger card of the same suit
-		return true
-	}
-
-	for c := range g.hand {
-		if c.Suit == g.cardPlayed.Suit {
-			// you're holding card of the requested suit that you must play
-			return false
-		}
-	}
-
-	if g.cardPlayed.Suit != g.trump && card.Suit == g.trump {
-		// you are forced to play trump card in this case
-		return true
-	}
-
-	if g.cardPlayed.Suit != g.trump {
-		for c := range g.hand {
-			if c.Suit == g.trump {
-				// you're holding a trump card that you should play
-				return false
-			}
-		}
-	}
-
-	// your move is valid
-	return true
-}
-
-func (g *game) update(screen *ebiten.Image) error {
-	screen.Fill(color.NRGBA{0x00, 0xaa, 0x00, 0xff})
-
-	if g.isOver {
-		if ebiten.IsDrawingSkipped() {
-			return nil
-		}
-
-		var message string
-		if g.score > g.opponentScore && g.score >= 66 {
-			message = "You win!"
-		} else if g.opponentScore > g.score && g.opponentScore >= 66 {
-			message = "You lose!"
-		} else if g.isClosed {
-			if g.opponentClosedGame {
-				message = "You win!"
-			} else {
-				message = "You lose!"
-			}
-		} else if g.isOpponentMove {
-			message = "You lose!"
-		} else {
-			message = "You win!"
-		}
-
-		text.Draw(screen, message, g.fontFaceBig, 300, 300, color.NRGBA{0xff, 0xff, 0xff, 0xff})
-
-		scores := fmt.Sprintf("%3s %3s", strconv.Itoa(g.score), strconv.Itoa(g.opponentScore))
-		text.Draw(screen, scores, g.fontFaceBig, 300, 360, color.NRGBA{0xff, 0xff, 0xff, 0xff})
-		return nil
-	}
-
-	var objects []*card
-	cardX := 270
-	z := 0
-	for _, card := range g.getHand() {
-		func(card santase.Card) {
-			objects = append(objects, g.newCard(&card, cardX, 600, z, false))
-			cardX += 80
-			z++
-		}(card)
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		simulate(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "host" {
+		host(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "watch" {
+		watch(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+
+	autoClaim := flag.Bool("auto-claim", false, "end the deal as soon as a player collects 66 points")
+	seed := flag.Int64("seed", 0, "seed for shuffling the cards; a random seed is used if 0")
+	deal := flag.String("deal", "", "deck of the first deal, for example 9CJCQCKCTCAC9DJDQDKDTDAD9HJHQHKHTHAH9SJSQSKSTSAS")
+	opponent := flag.String("opponent", "ismcts", "agent playing as your opponent, for example ismcts:c=5.4,budget=2s")
+	player := flag.String("player", "human", "agent playing in your place; human lets you play")
+	load := flag.String("load", "", "continue the deal saved in the file")
+	replay := flag.String("replay", "", "step through the deal saved in the file instead of playing")
+	animationSpeed := flag.Float64("animation-speed", 1, "speed of the card animations, for example 2 for twice as fast; 0 moves the cards instantly")
+	records := flag.String("records", "records", "directory the deals are saved to when they end or when you save them")
+	size := flag.String("size", "960x720", "size of the window; press F11 to switch to fullscreen")
+	connect := flag.String("connect", "", "play against another player over the network on the server at the address, for example example.com:6666 (see the host subcommand)")
+	watchAddr := flag.String("watch", "", "watch the match on the server or broadcast at the address without playing in it")
+	hands := flag.Bool("hands", false, "show both hands and the stack while watching with --watch")
+	broadcast := flag.String("broadcast", "", "let spectators watch the match on the address, for example :6667")
+	ui := flag.String("ui", "gui", "front end: gui plays in a window and tui in the terminal, which needs no display")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), "\nAvailable agents:\n%s", agents.Usage())
+		fmt.Fprintf(flag.CommandLine.Output(), "\nSubcommands:\n  simulate\n    \tplay agents against each other without a window (see %s simulate -h)\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "  host\n    \thold a match between two players playing over the network (see %s host -h)\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "  watch\n    \tprint the events of a match watched over the network without a window (see %s watch -h)\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "  serve\n    \tlet programs play against an agent through an HTTP JSON API (see %s serve -h)\n", os.Args[0])
+	}
+	flag.Parse()
+
+	opts := settings{
+		rules:          engine.Rules{AutoClaim: *autoClaim},
+		seed:           *seed,
+		recordsDir:     *records,
+		animationSpeed: *animationSpeed,
+	}
+	if _, err := fmt.Sscanf(*size, "%dx%d", &opts.width, &opts.height); err != nil || opts.width <= 0 || opts.height <= 0 {
+		log.Fatalf("invalid --size %q: expected width and height in pixels, for example 1280x720", *size)
+	}
+	switch {
+	case *ui != "gui" && *ui != "tui":
+		log.Fatalf("invalid --ui %q: expected gui or tui", *ui)
+	case *ui == "tui" && (*replay != "" || *connect != "" || *watchAddr != ""):
+		log.Fatal("--ui=tui plays only local matches and cannot be used with --replay, --connect or --watch")
+	}
+
+	if *replay != "" {
+		record, err := loadRecord(*replay)
+		if err != nil {
+			log.Fatalf("invalid --replay: %v", err)
+		}
+		runReplay(record, opts)
+		return
+	}
+
+	if *connect != "" {
+		client, err := network.Dial(*connect)
+		if err != nil {
+			log.Fatalf("cannot connect to %s: %v", *connect, err)
+		}
+		opts.connect = *connect
+		runNetworkGUI(client, opts)
+		client.Close()
+		return
+	}
+
+	if *watchAddr != "" {
+		opts.spectate = network.SpectatePublic
+		if *hands {
+			opts.spectate = network.SpectateHands
+		}
+		client, err := network.Watch(*watchAddr, opts.spectate)
+		if err != nil {
+			log.Fatalf("cannot watch %s: %v", *watchAddr, err)
+		}
+		opts.connect = *watchAddr
+		runNetworkGUI(client, opts)
+		client.Close()
+		return
+	}
+
+	if opts.seed == 0 {
+		opts.seed = time.Now().UnixNano()
+	}
+	if *deal != "" {
+		deck, err := engine.ParseDeck(*deal)
+		if err != nil {
+			log.Fatalf("invalid --deal: %v", err)
+		}
+		opts.deal = deck
 	}
-
-	cardX = 270
-	z = 0
-	for _, card := range g.getOpponentHand() {
-		func(card santase.Card) {
-			objects = append(objects, g.newCard(&card, cardX, 120, z, false))
-			cardX += 80
-			z++
-		}(card)
+	if *load != "" {
+		record, err := loadRecord(*load)
+		if err != nil {
+			log.Fatalf("invalid --load: %v", err)
+		}
+		opts.record = record
+		opts.rules = record.Rules
 	}
-
-	if g.trumpCard != nil {
-		if g.isClosed {
-			objects = append(objects, g.newCard(&g.stack[len(g.stack)-1], 84, 360, 0, false))
-			objects = append(objects, g.newCard(g.trumpCard, 120, 360, 1, true))
-		} else {
-			objects = append(objects, g.newCard(g.trumpCard, 120, 360, 0, true))
-			objects = append(objects, g.newCard(&g.stack[len(g.stack)-1], 84, 360, 1, false))
-		}
-	}
-
-	if g.cardPlayed != nil {
-		var x, y int
-		if g.opponentPlayedFirst {
-			x, y = 500, 340
-		} else {
-			x, y = 540, 360
-		}
-		objects = append(objects, g.newCard(g.cardPlayed, x, y, 0, false))
+
+	opponentAgent, err := agents.New(*opponent)
+	if err != nil {
+		log.Fatalf("invalid --opponent: %v", err)
 	}
 
-	if g.response != nil {
-		var x, y int
-		if g.opponentPlayedFirst {
-			x, y = 540, 360
-		} else {
-			x, y = 500, 340
+	// a nil player agent lets the user play
+	var playerAgent *santase.Agent
+	if *player != "human" {
+		agent, err := agents.New(*player)
+		if err != nil {
+			log.Fatalf("invalid --player: %v", err)
 		}
-		objects = append(objects, g.newCard(g.response, x, y, 1, false))
+		playerAgent = &agent
 	}
 
-	x, y := ebiten.CursorPosition()
-
-	if !g.debugBtnPressedFlag && ebiten.IsKeyPressed(ebiten.KeyF12) {
-		g.debugBtnPressedFlag = ebiten.IsKeyPressed(ebiten.KeyF12)
-		g.debugMode = !g.debugMode
-	}
-	g.debugBtnPressedFlag = ebiten.IsKeyPressed(ebiten.KeyF12)
-
-	if g.playerAI == nil {
-		var selected *card
-		for _, obj := range objects {
-			if obj.intersects(x, y) && (selected == nil || selected.zIndex < obj.zIndex) {
-				selected = obj
-			}
-		}
-
-		if selected != nil && !g.blockUI && !g.isOpponentMove &&
-			ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
-			if g.hand.HasCard(*selected.card) && g.isCardLegal(*selected.card) {
-				var move santase.Move
-				var isAnnouncement bool
-				if (selected.card.Rank == santase.Queen || selected.card.Rank == santase.King) &&
-					g.cardPlayed == nil && len(g.stack) < 11 {
-					var other santase.Card
-					if selected.card.Rank == santase.Queen {
-						other = santase.NewCard(santase.King, selected.card.Suit)
-					} else {
-						other = santase.NewCard(santase.Queen, selected.card.Suit)
-					}
-					if g.hand.HasCard(other) {
-						isAnnouncement = true
-						if selected.card.Suit == g.trump {
-							g.score += 40
-						} else {
-							g.score += 20
-						}
-					}
-				}
-
-				move = santase.Move{
-					Card: *selected.card,
-				}
-
-				if g.switchTrumpCard {
-					move.SwitchTrumpCard = true
-					g.switchTrumpCard = false
-				}
-
-				if isAnnouncement {
-					move.IsAnnouncement = true
-				}
-
-				if g.closeGame {
-					move.CloseGame = true
-					g.closeGame = false
-				}
-
-				g.userMoves <- move
-			} else if !g.isClosed && selected.card == g.trumpCard && len(g.stack) > 1 && len(g.stack) < 11 {
-				nineTrump := santase.NewCard(santase.Nine, g.trump)
-				if g.hand.HasCard(nineTrump) {
-					g.hand.RemoveCard(nineTrump)
-					g.hand.AddCard(*g.trumpCard)
-					g.trumpCard = &nineTrump
-					g.switchTrumpCard = true
-				}
-			} else if !g.isClosed && len(g.stack) > 1 && len(g.stack) < 11 && selected.card == &g.stack[len(g.stack)-1] {
-				g.isClosed = true
-				g.opponentClosedGame = false
-				g.closeGame = true
-			}
-		}
-
-		if selected != nil && !g.isOpponentMove && !g.blockUI &&
-			g.hand.HasCard(*selected.card) && g.isCardLegal(*selected.card) {
-			selected.y -= 20
-			selected.rect.Sub(image.Pt(0, -20))
+	if *broadcast != "" {
+		l, err := net.Listen("tcp", *broadcast)
+		if err != nil {
+			log.Fatalf("invalid --broadcast: %v", err)
 		}
+		log.Printf("spectators can watch on %v", l.Addr())
+		opts.spectators = network.NewBroadcast()
+		go opts.spectators.Serve(l)
 	}
 
-	if ebiten.IsDrawingSkipped() {
-		return nil
-	}
-
-	for _, obj := range objects {
-		obj.draw(screen)
+	if *ui == "tui" {
+		err = runTUI(opponentAgent, playerAgent, opts)
+	} else {
+		runGUI(opponentAgent, playerAgent, opts)
 	}
-
-	text.Draw(screen, "Score:"+strconv.Itoa(g.score), g.fontFace, 760, 680, color.White)
-
-	if g.trumpCard != nil {
-		text.Draw(screen, strconv.Itoa(1+len(g.stack))+" cards", g.fontFaceSmall, 20, 490, color.White)
+	if opts.spectators != nil {
+		opts.spectators.Close()
 	}
-
-	if g.debugMode {
-		text.Draw(screen, "Score:"+strconv.Itoa(g.opponentScore), g.fontFace, 760, 40, color.White)
+	agents.Close(opponentAgent)
+	if playerAgent != nil {
+		agents.Close(*playerAgent)
 	}
-
-	if g.announcement != 0 {
-		var x, y int
-		if g.isOpponentMove {
-			x, y = 650, 450
-		} else {
-			x, y = 275, 300
-		}
-		text.Draw(screen, strconv.Itoa(g.announcement), g.fontFaceBig, x, y, color.NRGBA{0xff, 0x00, 0x00, 0xff})
+	if err != nil {
+		log.Fatal(err)
 	}
-
-	return nil
 }
 
-func (g *game) playAIMove(opponent bool) {
-	var ai *santase.Game
-	var hand santase.Hand
-	var score *int
-
-	if opponent {
-		ai = &g.opponentAI
-		hand = g.opponentHand
-		score = &g.opponentScore
-	} else {
-		ai = g.playerAI
-		hand = g.hand
-		score = &g.score
+// startMatch starts a match of the user against the opponent agent with
+// the settings. The moves of the user are played by playerAgent if it is
+// not nil.
+func startMatch(opponentAgent santase.Agent, playerAgent *santase.Agent, opts settings) (*table.Match, error) {
+	matchOpts := table.MatchOptions{
+		Rules:      opts.rules,
+		Seed:       opts.seed,
+		Deck:       opts.deal,
+		Record:     opts.record,
+		RecordsDir: opts.recordsDir,
 	}
-
-	move := ai.GetMove()
-	if !opponent {
-		g.opponentAI.UpdateOpponentMove(move)
-	} else if g.playerAI != nil {
-		g.playerAI.UpdateOpponentMove(move)
+	// a nil *network.Broadcast in the interface would not be nil
+	if opts.spectators != nil {
+		matchOpts.Spectators = opts.spectators
 	}
 
-	if move.SwitchTrumpCard {
-		g.blockUI = true
-		nineTrump := santase.NewCard(santase.Nine, g.trump)
-		hand.RemoveCard(nineTrump)
-		hand.AddCard(*g.trumpCard)
-		g.trumpCard = &nineTrump
-		<-time.After(2 * time.Second)
-		g.blockUI = false
-	}
-	if move.CloseGame {
-		g.blockUI = true
-		g.isClosed = true
-		g.opponentClosedGame = opponent
-		<-time.After(2 * time.Second)
-		g.blockUI = false
-	}
-	if move.IsAnnouncement {
-		if move.Card.Suit == g.trump {
-			*score += 40
-			g.announcement = 40
-		} else {
-			*score += 20
-			g.announcement = 20
-		}
-
-		if *score >= 66 {
-			g.isOver = true
-		}
-	} else {
-		g.announcement = 0
-	}
-	hand.RemoveCard(move.Card)
-
-	if g.cardPlayed == nil {
-		g.opponentPlayedFirst = opponent
-		g.isOpponentMove = !opponent
-		g.cardPlayed = &move.Card
-		if !opponent || g.playerAI != nil {
-			g.playAIMove(!opponent)
-		}
-	} else {
-		g.playResponse(&move.Card)
+	agents := [2]santase.Agent{engine.PlayerTwo: opponentAgent}
+	if playerAgent != nil {
+		agents[engine.PlayerOne] = *playerAgent
 	}
+	return table.NewMatch(matchOpts, agents)
 }
 
-func (g *game) handleUserMoves() {
-	for move := range g.userMoves {
-		g.hand.RemoveCard(move.Card)
-		if move.IsAnnouncement {
-			if move.Card.Suit == g.trump {
-				g.announcement = 40
-			} else {
-				g.announcement = 20
-			}
-
-			if g.score >= 66 {
-				g.isOver = true
-				return
-			}
-		} else {
-			g.announcement = 0
-		}
-		g.opponentAI.UpdateOpponentMove(move)
-
-		if g.cardPlayed == nil {
-			g.opponentPlayedFirst = false
-			g.isOpponentMove = true
-			g.cardPlayed = &move.Card
-			g.playAIMove(true)
-		} else {
-			g.playResponse(&move.Card)
-		}
+// loadRecord reads a saved deal and checks that its moves are legal.
+func loadRecord(name string) (*engine.Record, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
 	}
-}
-
-func (g *game) Start() {
-	go g.handleUserMoves()
 
-	if g.isOpponentMove {
-		go g.playAIMove(true)
-	} else if g.playerAI != nil {
-		go g.playAIMove(false)
+	record := new(engine.Record)
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
 	}
-
-	if err := ebiten.Run(g.update, 960, 720, 1, "Santase"); err != nil {
-		panic(err)
+	if _, _, err := record.Replay([2]santase.Agent{}); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
 	}
-}
-
-func main() {
-	// initialize opponent agent
-	opponentAgent := ismcts.NewAgent(5.4, 2*time.Second)
-
-	// optionally initialize another agent to play two different
-	// AIs against each other
-	// randomAgent := random.NewAgent()
-	// game := NewGame(ismctsAgent, &randomAgent)
-
-	// otherwise pass nil as second argument to let the user play
-	game := NewGame(opponentAgent, nil)
-	game.Start()
+	return record, nil
 }
@@ -0,0 +1,329 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/inpututil"
+	"github.com/hajimehoshi/ebiten/text"
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/engine"
+	"github.com/nvlbg/santase-gui/network"
+	"github.com/nvlbg/santase-gui/table"
+)
+
+// maxScoreboardRows is the number of most recent deals shown on the
+// scoreboard between deals.
+const maxScoreboardRows = 8
+
+// match shows the deals of a match run by a table.Match and the scoreboard
+// between them, and lets the user start the next deal. In a match played
+// or watched over a network the deals come from the server and cannot be
+// replayed, saved or abandoned.
+type match struct {
+	*resources
+	settings
+	match  *table.Match
+	game   *game
+	window *window
+
+	// client is connected to the server of a match played over a network
+	// and waitingNext is whether the user asked for the next deal of it
+	client      *network.Client
+	waitingNext bool
+}
+
+func newMatch(opponentAgent santase.Agent, playerAgent *santase.Agent, opts settings) *match {
+	local, err := startMatch(opponentAgent, playerAgent, opts)
+	if err != nil {
+		panic(err)
+	}
+	return &match{
+		resources: loadResources(),
+		settings:  opts,
+		match:     local,
+		window:    &window{width: opts.width, height: opts.height},
+	}
+}
+
+func newNetworkMatch(client *network.Client, opts settings) *match {
+	return &match{
+		resources: loadResources(),
+		settings:  opts,
+		match:     table.NewRemoteMatch(opts.rules),
+		window:    &window{width: opts.width, height: opts.height},
+		client:    client,
+	}
+}
+
+// showDeal creates the game that shows the current deal of the match if
+// the deal has been replaced since the last frame.
+func (m *match) showDeal() {
+	deal := m.match.Deal()
+	if deal == nil || (m.game != nil && m.game.deal == deal) {
+		return
+	}
+
+	debugMode := false
+	if m.game != nil {
+		debugMode = m.game.debugMode
+	}
+	m.game = NewGame(m.resources, deal)
+	m.game.debugMode = debugMode
+	m.game.animator = newAnimator(m.animationSpeed)
+}
+
+// startRemoteDeal starts a deal played over a network.
+func (m *match) startRemoteDeal(d *network.Deal) {
+	log.Printf("deal %d", d.Number())
+
+	m.game = NewRemoteGame(m.resources, d)
+	m.game.debugMode = m.spectate == network.SpectateHands
+	m.game.animator = newAnimator(m.animationSpeed)
+	m.match.StartRemote(m.game.deal)
+	m.waitingNext = false
+}
+
+// status returns the line shown at the bottom of the screen: the seed of
+// the deals or the state of the connection to the server.
+func (m *match) status() string {
+	switch {
+	case m.client == nil:
+		return fmt.Sprintf("Seed %d", m.seed)
+	case m.client.Err() != nil:
+		return fmt.Sprintf("Disconnected: %v", m.client.Err())
+	case !m.client.Connected():
+		return fmt.Sprintf("Reconnecting to %s...", m.connect)
+	case m.spectate != "":
+		return fmt.Sprintf("Watching %s", m.connect)
+	}
+	return fmt.Sprintf("Connected to %s", m.connect)
+}
+
+// nextPressed returns whether the user asked for the next deal in this
+// frame. Only presses that start in this frame count, so a key or button
+// still held from the last move of the deal does not skip its result.
+func (m *match) nextPressed() bool {
+	return inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeySpace) ||
+		m.pointer.JustPressed
+}
+
+// menuButtons returns the buttons of the menu shown while a deal is played,
+// indexed by their rows.
+func (m *match) menuButtons() []*button {
+	local := m.client == nil
+	buttons := []*button{
+		saveRow:    {label: "Save game", key: ebiten.KeyF2, enabled: local},
+		replayRow:  {label: "Replay deal", key: ebiten.KeyR, enabled: local},
+		newGameRow: {label: "New game", key: ebiten.KeyN, enabled: local},
+		quitRow:    {label: "Quit", key: ebiten.KeyQ, enabled: true},
+	}
+	for row, b := range buttons {
+		b.rect = m.layout.menuButton(row)
+	}
+	return buttons
+}
+
+func (m *match) update(screen *ebiten.Image) error {
+	m.resize(screen)
+	m.pointer.update(m.layout)
+	l := m.layout
+
+	if m.client != nil {
+		select {
+		case d := <-m.client.Deals():
+			m.startRemoteDeal(d)
+		default:
+		}
+	}
+	m.showDeal()
+	if m.game == nil {
+		if !ebiten.IsDrawingSkipped() {
+			m.drawWaiting(screen)
+		}
+		return nil
+	}
+
+	if !m.game.deal.IsOver() {
+		buttons := m.menuButtons()
+		switch {
+		case buttons[quitRow].pressed(&m.pointer):
+			return errQuit
+		case buttons[replayRow].pressed(&m.pointer):
+			return m.match.ReplayDeal()
+		case buttons[newGameRow].pressed(&m.pointer):
+			return m.match.NewGame()
+		case buttons[saveRow].pressed(&m.pointer):
+			if name, err := m.match.Save(); err != nil {
+				log.Printf("cannot save the game: %v", err)
+			} else {
+				log.Printf("game saved to %s; continue it with --load=%s", name, name)
+				message := "Game saved"
+				m.game.floatingText = &floatingText{text: message, x: l.centerText(message, fontSizeBig), y: l.middle(300)}
+			}
+		}
+		if err := m.game.update(screen); err != nil {
+			return err
+		}
+		if !ebiten.IsDrawingSkipped() {
+			text.Draw(screen, m.status(), m.fontFaceSmall, l.left(20), l.bottom(700), color.White)
+			for _, b := range buttons {
+				b.draw(screen, m.resources)
+			}
+		}
+		return nil
+	}
+
+	m.match.Update()
+
+	if m.client == nil && inpututil.IsKeyJustPressed(ebiten.KeyR) {
+		return m.match.ReplayDeal()
+	}
+
+	if m.nextPressed() && !m.waitingNext && m.spectate == "" {
+		if m.client != nil {
+			m.client.Next()
+			m.waitingNext = true
+		} else {
+			return m.match.NextDeal()
+		}
+	}
+
+	if ebiten.IsDrawingSkipped() {
+		return nil
+	}
+
+	m.drawScoreboard(screen)
+	return nil
+}
+
+func (m *match) drawScoreboard(screen *ebiten.Image) {
+	screen.Fill(color.NRGBA{0x00, 0xaa, 0x00, 0xff})
+	l := m.layout
+	white := color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	state := m.match.State()
+	lastDeal, _ := m.match.Result()
+
+	var message string
+	switch {
+	case m.spectate != "" && state.IsOver():
+		message = fmt.Sprintf("%s wins the match!", playerName(state.Winner()))
+	case m.spectate != "":
+		message = fmt.Sprintf("%s wins!", playerName(lastDeal.Winner))
+	case state.IsOver() && state.Winner() == engine.PlayerOne:
+		message = "You win the match!"
+	case state.IsOver():
+		message = "You lose the match!"
+	case lastDeal.Winner == engine.PlayerOne:
+		message = "You win!"
+	default:
+		message = "You lose!"
+	}
+	text.Draw(screen, message, m.fontFaceBig, l.centerText(message, fontSizeBig), l.middle(120), white)
+
+	if p, claimed := m.game.deal.State().ClaimedBy(); claimed && !m.game.deal.State().IsClaimValid() {
+		reason := "Opponent claimed 66 falsely"
+		switch {
+		case m.spectate != "":
+			reason = fmt.Sprintf("%s claimed 66 falsely", playerName(p))
+		case p == engine.PlayerOne:
+			reason = "You claimed 66 falsely"
+		}
+		text.Draw(screen, reason, m.fontFaceSmall, l.centerText(reason, fontSizeSmall), l.middle(220), white)
+	}
+
+	points := fmt.Sprintf("+%d game points", lastDeal.GamePoints)
+	if lastDeal.GamePoints == 1 {
+		points = "+1 game point"
+	}
+	if !m.match.Counted() {
+		points = "Practice deal - not counted"
+	}
+	text.Draw(screen, points, m.fontFace, l.centerText(points, fontSize), l.middle(180), white)
+
+	tableX := l.centerX(216)
+	header := fmt.Sprintf("%-6s %9s %6s %9s", "Deal", "Points", "You", "Opponent")
+	if m.spectate != "" {
+		header = fmt.Sprintf("%-6s %9s %6s %9s", "Deal", "Points", "One", "Two")
+	}
+	text.Draw(screen, header, m.fontFaceSmall, tableX, l.middle(260), white)
+
+	for i, row := range m.match.Scoreboard(maxScoreboardRows) {
+		line := fmt.Sprintf("%-6d %4d:%-4d %6d %9d",
+			row.Deal, row.Scores[engine.PlayerOne], row.Scores[engine.PlayerTwo],
+			row.GamePoints[engine.PlayerOne], row.GamePoints[engine.PlayerTwo])
+		text.Draw(screen, line, m.fontFaceSmall, tableX, l.middle(300+i*32), white)
+	}
+
+	total := fmt.Sprintf("%-6s %9s %6d %9d", "Total", "",
+		state.GamePoints(engine.PlayerOne), state.GamePoints(engine.PlayerTwo))
+	text.Draw(screen, total, m.fontFaceSmall, tableX, l.middle(316+maxScoreboardRows*32), white)
+
+	var prompt string
+	switch {
+	case m.spectate != "":
+		prompt = "Waiting for the next deal"
+	case m.waitingNext:
+		prompt = "Waiting for your opponent"
+	case state.IsOver():
+		prompt = "Press Enter or click to start a new match"
+	default:
+		prompt = "Press Enter or click for the next deal"
+	}
+	text.Draw(screen, prompt, m.fontFaceSmall, l.centerText(prompt, fontSizeSmall), l.middle(660), white)
+
+	replay := fmt.Sprintf("Press R to replay this deal (seed %d)", m.seed)
+	if m.client != nil {
+		replay = m.status()
+	}
+	text.Draw(screen, replay, m.fontFaceSmall, l.centerText(replay, fontSizeSmall), l.middle(690), white)
+}
+
+// drawWaiting shows that the match played over a network has not started
+// yet.
+func (m *match) drawWaiting(screen *ebiten.Image) {
+	screen.Fill(color.NRGBA{0x00, 0xaa, 0x00, 0xff})
+	l := m.layout
+
+	message := "Waiting for your opponent to join"
+	if m.spectate != "" {
+		message = "Waiting for the players"
+	}
+	text.Draw(screen, message, m.fontFace, l.centerText(message, fontSize), l.middle(340), color.White)
+	status := m.status()
+	text.Draw(screen, status, m.fontFaceSmall, l.centerText(status, fontSizeSmall), l.middle(390), color.White)
+}
+
+// Start opens the window and runs the match.
+func (m *match) Start() {
+	err := m.window.run(m.update, "Santase")
+	m.match.Close()
+	if err != nil {
+		panic(err)
+	}
+}
+
+// runGUI opens the window and plays a match against the opponent agent.
+func runGUI(opponentAgent santase.Agent, playerAgent *santase.Agent, opts settings) {
+	newMatch(opponentAgent, playerAgent, opts).Start()
+}
+
+// runNetworkGUI opens the window and plays a match over a network with the
+// client connected to its server.
+func runNetworkGUI(client *network.Client, opts settings) {
+	newNetworkMatch(client, opts).Start()
+}
+
+// playerName returns the name of the player shown to spectators.
+func playerName(p engine.Player) string {
+	if p == engine.PlayerOne {
+		return "Player one"
+	}
+	return "Player two"
+}
@@ -0,0 +1,67 @@
+// Package mouse follows the left mouse button from the moment it is
+// pressed until it is released and tells a click from a drag. It is given
+// the state of the mouse in every frame instead of reading it, so it can
+// be used and tested without a display.
+package mouse
+
+// Pointer is the state of the left mouse button. Every press and release
+// is reported in exactly one frame, however long the button is held.
+type Pointer struct {
+	// X and Y are the position of the cursor in this frame
+	X int
+	Y int
+
+	// JustPressed and JustReleased are whether the button was pressed or
+	// released in this frame
+	JustPressed  bool
+	JustReleased bool
+
+	// pressX and pressY are where the button was pressed last
+	pressX int
+	pressY int
+
+	held     bool
+	dragging bool
+}
+
+// Update takes the state of the mouse in this frame: the position of the
+// cursor and whether the button was pressed or released in it. The press
+// becomes a drag once the cursor moves more than dragDistance away from
+// where the button was pressed. It must be called once at the start of
+// every frame.
+func (p *Pointer) Update(x, y int, justPressed, justReleased bool, dragDistance int) {
+	if p.JustReleased {
+		p.held, p.dragging = false, false
+	}
+
+	p.X, p.Y = x, y
+	p.JustPressed = justPressed
+	p.JustReleased = justReleased
+
+	if p.JustPressed {
+		p.pressX, p.pressY = p.X, p.Y
+		p.held, p.dragging = true, false
+	}
+	if p.held && !p.dragging {
+		dx, dy := p.X-p.pressX, p.Y-p.pressY
+		p.dragging = dx*dx+dy*dy > dragDistance*dragDistance
+	}
+}
+
+// IsDragging returns whether the button is held and the cursor has moved
+// since it was pressed.
+func (p *Pointer) IsDragging() bool {
+	return p.dragging && !p.JustReleased
+}
+
+// Clicked returns whether the button was released in this frame without
+// the cursor moving since it was pressed.
+func (p *Pointer) Clicked() bool {
+	return p.JustReleased && p.held && !p.dragging
+}
+
+// Dropped returns whether the button was released in this frame at the
+// end of a drag.
+func (p *Pointer) Dropped() bool {
+	return p.JustReleased && p.dragging
+}
@@ -0,0 +1,82 @@
+package mouse
+
+import "testing"
+
+// frame is the state of the mouse in a frame and what the pointer should
+// report in it.
+type frame struct {
+	x, y              int
+	pressed, released bool
+
+	clicked, dragging, dropped bool
+}
+
+func TestPointer(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames []frame
+	}{
+		{"click", []frame{
+			{x: 10, y: 10, pressed: true},
+			{x: 10, y: 10},
+			{x: 10, y: 10, released: true, clicked: true},
+			{x: 10, y: 10},
+		}},
+		{"click in one frame", []frame{
+			{x: 10, y: 10, pressed: true, released: true, clicked: true},
+			{x: 10, y: 10},
+		}},
+		{"click while the cursor shakes", []frame{
+			{x: 10, y: 10, pressed: true},
+			{x: 13, y: 14},
+			{x: 14, y: 13, released: true, clicked: true},
+		}},
+		{"drag and drop", []frame{
+			{x: 10, y: 10, pressed: true},
+			{x: 15, y: 15, dragging: true},
+			{x: 50, y: 80, dragging: true},
+			{x: 60, y: 90, released: true, dropped: true},
+			{x: 60, y: 90},
+		}},
+		// a drag is cancelled by dropping the card where it was taken
+		// from, which must not count as a click on it
+		{"drag cancelled at the start", []frame{
+			{x: 10, y: 10, pressed: true},
+			{x: 30, y: 10, dragging: true},
+			{x: 10, y: 10, dragging: true},
+			{x: 10, y: 10, released: true, dropped: true},
+		}},
+		{"release without a press", []frame{
+			{x: 10, y: 10, released: true},
+			{x: 10, y: 10},
+		}},
+		{"press after a drop", []frame{
+			{x: 10, y: 10, pressed: true},
+			{x: 30, y: 10, dragging: true},
+			{x: 30, y: 10, released: true, dropped: true},
+			{x: 30, y: 10, pressed: true},
+			{x: 30, y: 10, released: true, clicked: true},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var p Pointer
+			for i, f := range test.frames {
+				p.Update(f.x, f.y, f.pressed, f.released, 6)
+				if p.X != f.x || p.Y != f.y || p.JustPressed != f.pressed || p.JustReleased != f.released {
+					t.Errorf("frame %d: the pointer is %+v, want the state of the mouse %+v", i, p, f)
+				}
+				if got := p.Clicked(); got != f.clicked {
+					t.Errorf("frame %d: Clicked() = %v, want %v", i, got, f.clicked)
+				}
+				if got := p.IsDragging(); got != f.dragging {
+					t.Errorf("frame %d: IsDragging() = %v, want %v", i, got, f.dragging)
+				}
+				if got := p.Dropped(); got != f.dropped {
+					t.Errorf("frame %d: Dropped() = %v, want %v", i, got, f.dropped)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package network
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// spectatorQueue is the number of messages waiting to be sent to a
+// spectator after which the spectator is disconnected, so that a slow
+// spectator never holds up the game.
+const spectatorQueue = 256
+
+// Broadcast shows a series of deals to spectators connected over TCP. The
+// deals are played elsewhere and shown with Show; the spectators only
+// watch them.
+type Broadcast struct {
+	mu         sync.Mutex
+	spectators map[*spectator]bool
+
+	// last holds the last deal shown in each spectator mode, which is
+	// sent to spectators when they join
+	last map[string]message
+
+	listener net.Listener
+	closed   bool
+}
+
+// spectator is a connected spectator whose messages are sent on a separate
+// goroutine.
+type spectator struct {
+	conn  *conn
+	mode  string
+	queue chan message
+}
+
+// NewBroadcast creates a broadcast without spectators.
+func NewBroadcast() *Broadcast {
+	return &Broadcast{
+		spectators: make(map[*spectator]bool),
+		last:       make(map[string]message),
+	}
+}
+
+// ListenAndServe listens on the TCP address and serves the spectators
+// that connect to it until the broadcast is closed.
+func (b *Broadcast) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return b.Serve(l)
+}
+
+// Serve serves the spectators that connect to the listener until the
+// broadcast is closed. It returns nil when the broadcast is closed.
+func (b *Broadcast) Serve(l net.Listener) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		l.Close()
+		return nil
+	}
+	b.listener = l
+	b.mu.Unlock()
+
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			b.mu.Lock()
+			closed := b.closed
+			b.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		go b.serve(newConn(c))
+	}
+}
+
+// Close stops listening and disconnects the spectators.
+func (b *Broadcast) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for s := range b.spectators {
+		s.conn.Close()
+	}
+	if b.listener != nil {
+		return b.listener.Close()
+	}
+	return nil
+}
+
+// Show sends the deal with the passed number to the spectators after the
+// events, or when it starts if there are none. The record holds the moves
+// played so far; it is not kept, so the caller may go on adding moves to
+// it.
+func (b *Broadcast) Show(deal int, record *engine.Record, events []engine.Event) {
+	state, _, err := record.Replay([2]santase.Agent{})
+	if err != nil {
+		log.Printf("cannot show deal %d: %v", deal, err)
+		return
+	}
+	view := state.PublicView()
+	messages := map[string]message{
+		SpectateHands:  {Type: "state", Deal: deal, Record: copyRecord(record), Events: events},
+		SpectatePublic: {Type: "state", Deal: deal, View: &view, Events: engine.PublicEvents(events)},
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for mode, m := range messages {
+		last := m
+		last.Events = nil
+		b.last[mode] = last
+	}
+	for s := range b.spectators {
+		b.send(s, messages[s.mode])
+	}
+}
+
+// serve reads the hello message of a spectator and lets them watch.
+func (b *Broadcast) serve(c *conn) {
+	defer c.Close()
+
+	c.SetReadDeadline(time.Now().Add(helloTimeout))
+	hello, err := c.receive()
+	if err != nil {
+		return
+	}
+	c.SetReadDeadline(time.Time{})
+	if hello.Type != "hello" || hello.Spectate == "" {
+		c.send(message{Type: "error", Error: "only spectators can connect"})
+		return
+	}
+	b.watch(c, hello.Spectate)
+}
+
+// watch sends the deals to the spectator until the connection is lost.
+func (b *Broadcast) watch(c *conn, mode string) {
+	if mode != SpectateHands && mode != SpectatePublic {
+		c.send(message{Type: "error", Error: fmt.Sprintf("unknown spectator mode %q, expected %s or %s", mode, SpectateHands, SpectatePublic)})
+		return
+	}
+
+	s := &spectator{conn: c, mode: mode, queue: make(chan message, spectatorQueue)}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.spectators[s] = true
+	b.send(s, message{Type: "welcome"})
+	if m, ok := b.last[mode]; ok {
+		b.send(s, m)
+	}
+	b.mu.Unlock()
+	log.Printf("spectator joined from %v", c.RemoteAddr())
+
+	go func() {
+		for m := range s.queue {
+			if err := c.send(m); err != nil {
+				c.Close()
+			}
+		}
+	}()
+
+	for {
+		m, err := c.receive()
+		if err != nil {
+			break
+		}
+		b.send(s, message{Type: "error", Error: fmt.Sprintf("spectators cannot send %q", m.Type)})
+	}
+
+	b.mu.Lock()
+	delete(b.spectators, s)
+	close(s.queue)
+	b.mu.Unlock()
+	log.Printf("spectator from %v left", c.RemoteAddr())
+}
+
+// send queues the message for the spectator or disconnects them if they
+// are too slow to take it.
+func (b *Broadcast) send(s *spectator, m message) {
+	select {
+	case s.queue <- m:
+	default:
+		s.conn.Close()
+	}
+}
+
+// copyRecord returns a copy of the record that does not change when moves
+// are added to the original.
+func copyRecord(r *engine.Record) *engine.Record {
+	c := *r
+	c.Moves = append([]engine.Move(nil), r.Moves...)
+	return &c
+}
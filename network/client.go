@@ -0,0 +1,363 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/engine"
+	"github.com/nvlbg/santase-gui/table"
+)
+
+// reconnectDelay is how long the client waits before each attempt to
+// reconnect.
+const reconnectDelay = time.Second
+
+// Client is a player or a spectator connected to a server. When the
+// connection is lost it reconnects until it is closed.
+type Client struct {
+	addr     string
+	spectate string
+
+	mu        sync.Mutex
+	conn      *conn
+	token     string
+	connected bool
+	err       error
+
+	// next is whether the player asked for the next deal, which is asked
+	// for again after reconnecting until it starts
+	next bool
+
+	// deal is the deal being played; it is used only by the goroutine
+	// receiving the messages
+	deal *Deal
+
+	deals  chan *Deal
+	closed chan struct{}
+	once   sync.Once
+}
+
+// Dial connects to the server at the TCP address and joins the match.
+func Dial(addr string) (*Client, error) {
+	return dial(addr, "")
+}
+
+// Watch connects to the server or broadcast at the TCP address as a
+// spectator in the mode, SpectateHands or SpectatePublic. The deals of a
+// spectator are seen from the seat of engine.PlayerOne and cannot be
+// played.
+func Watch(addr string, mode string) (*Client, error) {
+	return dial(addr, mode)
+}
+
+func dial(addr string, spectate string) (*Client, error) {
+	c := &Client{
+		addr:     addr,
+		spectate: spectate,
+		deals:    make(chan *Deal, 1),
+		closed:   make(chan struct{}),
+	}
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	go c.run()
+	return c, nil
+}
+
+// Deals delivers each deal of the match when it starts, or when the client
+// connects while it is being played.
+func (c *Client) Deals() <-chan *Deal {
+	return c.deals
+}
+
+// Next tells the server that the player is ready for the next deal.
+func (c *Client) Next() {
+	c.mu.Lock()
+	c.next = true
+	c.mu.Unlock()
+	go c.send(message{Type: "next"})
+}
+
+// Connected returns whether the client is connected to the server.
+func (c *Client) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connected
+}
+
+// Err returns the error because of which the client stopped reconnecting,
+// for example when the server no longer knows the player, or nil.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close disconnects from the server.
+func (c *Client) Close() error {
+	c.once.Do(func() { close(c.closed) })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connected = false
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+// connect connects to the server and introduces the player.
+func (c *Client) connect() error {
+	nc, err := net.Dial("tcp", c.addr)
+	if err != nil {
+		return err
+	}
+	conn := newConn(nc)
+
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+
+	conn.SetDeadline(time.Now().Add(helloTimeout))
+	if err := conn.send(message{Type: "hello", Token: token, Spectate: c.spectate}); err != nil {
+		conn.Close()
+		return err
+	}
+	m, err := conn.receive()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+	switch m.Type {
+	case "welcome":
+	case "error":
+		conn.Close()
+		return refusal(m.Error)
+	default:
+		conn.Close()
+		return fmt.Errorf("expected welcome, got %q", m.Type)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.closed:
+		conn.Close()
+		return errors.New("the client is closed")
+	default:
+	}
+	c.conn, c.token, c.connected = conn, m.Token, true
+	return nil
+}
+
+// run receives the messages of the server and reconnects when the
+// connection is lost.
+func (c *Client) run() {
+	for {
+		c.mu.Lock()
+		conn := c.conn
+		c.mu.Unlock()
+
+		for {
+			m, err := conn.receive()
+			if err != nil {
+				break
+			}
+			c.handle(m)
+		}
+		conn.Close()
+
+		c.mu.Lock()
+		c.connected = false
+		c.mu.Unlock()
+		if !c.reconnect() {
+			return
+		}
+	}
+}
+
+// reconnect tries to reconnect until it succeeds, the client is closed or
+// the server refuses the player. It returns whether it has reconnected.
+func (c *Client) reconnect() bool {
+	for {
+		select {
+		case <-c.closed:
+			return false
+		case <-time.After(reconnectDelay):
+		}
+
+		err := c.connect()
+		if err == nil {
+			c.mu.Lock()
+			next := c.next
+			c.mu.Unlock()
+			if next {
+				c.send(message{Type: "next"})
+			}
+			return true
+		}
+		if _, ok := err.(refusal); ok {
+			log.Printf("cannot reconnect to %s: %v", c.addr, err)
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+			return false
+		}
+	}
+}
+
+// handle shows the player a message of the server.
+func (c *Client) handle(m message) {
+	switch m.Type {
+	case "state":
+		if m.View == nil && m.Record == nil {
+			log.Printf("state of deal %d without a view", m.Deal)
+			return
+		}
+		if c.deal == nil || c.deal.number != m.Deal {
+			c.startDeal(m)
+			return
+		}
+		c.deal.update(m)
+	case "error":
+		log.Printf("server: %s", m.Error)
+	}
+}
+
+// startDeal delivers a new deal, replacing one that has not been taken
+// from Deals yet.
+func (c *Client) startDeal(m message) {
+	c.mu.Lock()
+	c.next = false
+	c.mu.Unlock()
+
+	d := &Deal{
+		client:    c,
+		number:    m.Deal,
+		spectated: c.spectate != "",
+		updates:   make(chan table.Update, 64),
+		closed:    c.closed,
+	}
+	u, err := d.show(m)
+	if err != nil {
+		log.Printf("cannot show deal %d: %v", m.Deal, err)
+		return
+	}
+	d.state = u.State
+	c.deal = d
+	select {
+	case <-c.deals:
+	default:
+	}
+	c.deals <- c.deal
+}
+
+// send sends the message if the client is connected; otherwise it is
+// dropped.
+func (c *Client) send(m message) {
+	c.mu.Lock()
+	conn, connected := c.conn, c.connected
+	c.mu.Unlock()
+	if connected {
+		if err := conn.send(m); err != nil {
+			conn.Close()
+		}
+	}
+}
+
+// refusal is the reason for which the server did not let the player join.
+type refusal string
+
+func (r refusal) Error() string {
+	return string(r)
+}
+
+// Deal is a deal of the match as the player sees it. It is the remote of
+// a table.Deal that shows it.
+type Deal struct {
+	client    *Client
+	number    int
+	state     engine.State
+	spectated bool
+
+	// placement is where the last update put the hidden cards
+	placement placement
+
+	updates chan table.Update
+	closed  chan struct{}
+}
+
+// Number returns the number of the deal in the series played on the
+// server, counted from 1.
+func (d *Deal) Number() int {
+	return d.number
+}
+
+// State returns the deal as the player saw it when it was delivered. The
+// cards the player cannot see are replaced by other cards.
+func (d *Deal) State() engine.State {
+	return d.state
+}
+
+// IsSpectated returns whether the deal is watched by a spectator rather
+// than played.
+func (d *Deal) IsSpectated() bool {
+	return d.spectated
+}
+
+// Play implements table.Remote. The moves of a spectator are dropped.
+func (d *Deal) Play(m engine.Move) {
+	if d.spectated {
+		return
+	}
+	go d.client.send(message{Type: "move", Deal: d.number, Move: engine.FormatMove(m)})
+}
+
+// Updates implements table.Remote.
+func (d *Deal) Updates() <-chan table.Update {
+	return d.updates
+}
+
+// update delivers the deal as the player sees it after the events.
+func (d *Deal) update(m message) {
+	u, err := d.show(m)
+	if err != nil {
+		log.Printf("cannot show deal %d: %v", m.Deal, err)
+		return
+	}
+	select {
+	case d.updates <- u:
+	case <-d.closed:
+	}
+}
+
+// show returns the deal as the player sees it in the state message: the
+// record of the whole deal or the view of the player, with the cards they
+// cannot see placed after the last ones.
+func (d *Deal) show(m message) (table.Update, error) {
+	u := table.Update{Events: m.Events}
+	if m.Record != nil {
+		state, _, err := m.Record.Replay([2]santase.Agent{})
+		if err != nil {
+			return u, err
+		}
+		u.State = state
+		return u, nil
+	}
+
+	if err := m.View.Check(); err != nil {
+		return u, err
+	}
+	placement, revealed := d.placement.update(*m.View, m.Events)
+	d.placement = placement
+	u.State = placement.state(*m.View)
+	u.Revealed = revealed
+	return u, nil
+}
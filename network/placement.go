@@ -0,0 +1,161 @@
+package network
+
+import (
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// placement puts the cards a player cannot see into the hand of their
+// opponent and into the stack, so that a state can be made from a view.
+// In a public view the hand of the player is hidden as well. The cards
+// stand for the real ones only; a card keeps its place from one view to
+// the next for as long as possible, so that the cards shown face down do
+// not jump around.
+type placement struct {
+	hands [2][]santase.Card
+
+	// stack is the stack with the top card last
+	stack []santase.Card
+}
+
+// update places the hidden cards of the view after the events. It returns
+// the new placement and the cards that stood for cards in a hidden hand
+// and have turned out to be other cards, mapped to the cards they turned
+// out to be.
+func (pl placement) update(v engine.View, events []engine.Event) (placement, map[santase.Card]santase.Card) {
+	hidden := santase.NewPile()
+	for _, card := range v.Hidden() {
+		hidden.AddCard(card)
+	}
+	hand := santase.NewHand(v.Hand...)
+	counts := [2]int{v.HiddenHand, v.OpponentCards}
+	initial := len(pl.hands[engine.PlayerOne])+len(pl.hands[engine.PlayerTwo])+len(pl.stack) == 0
+
+	// revealedBy returns the player that showed a card that was hidden:
+	// the player that played it or switched it for the trump card
+	revealedBy := func(card santase.Card) engine.Player {
+		for _, e := range events {
+			switch e := e.(type) {
+			case engine.CardPlayed:
+				if e.Move.Card == card {
+					return e.Player
+				}
+			case engine.TrumpSwitched:
+				if card == santase.NewCard(santase.Nine, v.Trump) {
+					return e.Player
+				}
+			}
+		}
+		return engine.PlayerTwo
+	}
+
+	// takenBy returns the player that took the trump card: the player
+	// that switched it or else the one that drew the last card
+	takenBy := func() engine.Player {
+		for _, e := range events {
+			if e, ok := e.(engine.TrumpSwitched); ok {
+				return e.Player
+			}
+		}
+		if counts[engine.PlayerOne] == 0 {
+			return engine.PlayerTwo
+		}
+		return v.LastTrick.Other()
+	}
+
+	hands := [2][]santase.Card{
+		append([]santase.Card(nil), pl.hands[engine.PlayerOne]...),
+		append([]santase.Card(nil), pl.hands[engine.PlayerTwo]...),
+	}
+	stack := append([]santase.Card(nil), pl.stack...)
+	revealed := make(map[santase.Card]santase.Card)
+
+	// the cards revealed by a player whose hand is hidden, for example a
+	// card they played, came from their hand; if one of them was in the
+	// stack, another card from their hand is put in its place
+	for i, card := range stack {
+		if hidden.HasCard(card) || hand.HasCard(card) {
+			continue
+		}
+		p := revealedBy(card)
+		for j := len(hands[p]) - 1; j >= 0; j-- {
+			if other := hands[p][j]; hidden.HasCard(other) {
+				hands[p] = append(hands[p][:j], hands[p][j+1:]...)
+				stack[i] = other
+				revealed[other] = card
+				break
+			}
+		}
+	}
+	placed := santase.NewPile()
+	for p := range hands {
+		hands[p] = keep(hands[p], hidden)
+		for _, card := range hands[p] {
+			placed.AddCard(card)
+		}
+	}
+	stack = keep(stack, hidden)
+	for _, card := range stack {
+		placed.AddCard(card)
+	}
+
+	// the cards of a new deal are dealt to the hands and the stack; later
+	// the only card that can be hidden anew is the trump card
+	for _, card := range v.Hidden() {
+		p := takenBy()
+		switch {
+		case placed.HasCard(card):
+			continue
+		case initial && len(hands[engine.PlayerOne]) < counts[engine.PlayerOne]:
+			p = engine.PlayerOne
+		case initial:
+			p = engine.PlayerTwo
+		}
+		if len(hands[p]) < counts[p] {
+			hands[p] = append(hands[p], card)
+		} else {
+			stack = append([]santase.Card{card}, stack...)
+		}
+	}
+
+	// the winner of the last trick draws from the top of the stack first
+	for _, p := range []engine.Player{v.LastTrick, v.LastTrick.Other()} {
+		for len(hands[p]) < counts[p] && len(stack) > 0 {
+			hands[p] = append(hands[p], stack[len(stack)-1])
+			stack = stack[:len(stack)-1]
+		}
+	}
+	for p := range hands {
+		for len(hands[p]) > counts[p] {
+			stack = append(stack, hands[p][len(hands[p])-1])
+			hands[p] = hands[p][:len(hands[p])-1]
+		}
+	}
+
+	if len(revealed) == 0 {
+		revealed = nil
+	}
+	return placement{hands: hands, stack: stack}, revealed
+}
+
+// state returns the state of the view with the hidden cards in their
+// places.
+func (pl placement) state(v engine.View) engine.State {
+	var hidden []santase.Card
+	hidden = append(hidden, pl.hands[engine.PlayerOne]...)
+	hidden = append(hidden, pl.hands[engine.PlayerTwo]...)
+	hidden = append(hidden, pl.stack...)
+	return v.State(hidden)
+}
+
+// keep returns the cards that are in the pile.
+func keep(cards []santase.Card, pile santase.Pile) []santase.Card {
+	var result []santase.Card
+	for _, card := range cards {
+		if pile.HasCard(card) {
+			result = append(result, card)
+		}
+	}
+	return result
+}
@@ -0,0 +1,116 @@
+// Package network plays a match between two players over TCP. A Server
+// holds the real state of the deals and each player connects to it with a
+// Client, which learns only what the player can see: the cards in the hand
+// of their opponent and in the stack are never sent to it.
+//
+// Spectators can connect to a Server or to a Broadcast, which shows the
+// deals played elsewhere, for example in the window of a local game. They
+// choose whether to see both hands and the stack or only what is public,
+// and cannot send moves.
+//
+// The client and the server exchange JSON objects, one per line, each with
+// a "type" field. The client sends
+//
+//	{"type": "hello", "token": "..."}
+//		first after connecting; the token is empty when the player
+//		joins and the one the server gave them when they reconnect
+//	{"type": "hello", "spectate": "hands"}
+//		instead of the above from a spectator, with "hands" to see
+//		both hands and the stack or "public" to see neither; a server
+//		refuses "hands" unless it was allowed with Server.AllowHands
+//	{"type": "move", "deal": 1, "move": "~QS+"}
+//		the move of the player in the deal, written like the moves of a
+//		saved deal
+//	{"type": "next"}
+//		the player is ready for the next deal after a deal has ended
+//
+// and the server sends
+//
+//	{"type": "welcome", "token": "..."}
+//		the answer to hello with the token to reconnect with
+//	{"type": "state", "deal": 1, "view": {...}, "events": [...]}
+//		the deal as the player sees it (see engine.View) after every
+//		move and the events of the move (see engine.Events), or without
+//		events when a deal starts or the player needs to be brought up
+//		to date
+//	{"type": "error", "error": "..."}
+//		a message of the player was rejected; a state follows if a
+//		deal has started and the player stays connected
+//
+// The players are numbered from 1 (the player receiving the message) to
+// 2 (their opponent), so both clients see the deal the same way. A player
+// that loses the connection keeps their seat and gets the state of the
+// deal again when they reconnect with their token.
+//
+// Spectators get the same state messages with the players numbered as in
+// the deal. A spectator that sees both hands gets the record of the deal
+// so far (see engine.Record) in a "record" field instead of the view and
+// all events, and one that sees neither hand gets a public view and the
+// events without the cards drawn.
+package network
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// DefaultPort is the port the server listens on unless another one is
+// given.
+const DefaultPort = 6666
+
+// Spectator modes, which a spectator sends in its hello message.
+const (
+	// SpectateHands shows the spectator both hands and the stack.
+	SpectateHands = "hands"
+
+	// SpectatePublic shows the spectator only what both players see.
+	SpectatePublic = "public"
+)
+
+// writeTimeout is how long a message may take to be sent before the
+// connection is considered lost.
+const writeTimeout = 10 * time.Second
+
+// message is a line of the protocol.
+type message struct {
+	Type     string         `json:"type"`
+	Token    string         `json:"token,omitempty"`
+	Spectate string         `json:"spectate,omitempty"`
+	Deal     int            `json:"deal,omitempty"`
+	Move     string         `json:"move,omitempty"`
+	View     *engine.View   `json:"view,omitempty"`
+	Record   *engine.Record `json:"record,omitempty"`
+	Events   engine.Events  `json:"events,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// conn is a connection on which messages are sent from several
+// goroutines.
+type conn struct {
+	net.Conn
+	dec *json.Decoder
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newConn(c net.Conn) *conn {
+	return &conn{Conn: c, dec: json.NewDecoder(c), enc: json.NewEncoder(c)}
+}
+
+func (c *conn) send(m message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.enc.Encode(m)
+}
+
+func (c *conn) receive() (message, error) {
+	var m message
+	err := c.dec.Decode(&m)
+	return m, err
+}
@@ -0,0 +1,374 @@
+package network
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	mathrand "math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// helloTimeout is how long a new connection may take to introduce itself.
+const helloTimeout = 10 * time.Second
+
+// playerQueue is the number of messages waiting to be sent to a player
+// after which the player is disconnected, so that a slow player never
+// holds up the server. They get the deal again when they reconnect.
+const playerQueue = 64
+
+// Server holds the real state of a match between two players and plays
+// their moves. The first player to join plays as engine.PlayerOne and the
+// second as engine.PlayerTwo; the first deal starts when both have joined
+// and each of the next ones when both have asked for it. When a match is
+// over the next deal starts a new one. Spectators can watch the deals, but
+// they see both hands only if the server allows it with AllowHands.
+type Server struct {
+	rules      engine.Rules
+	rng        *mathrand.Rand
+	spectators *Broadcast
+
+	mu     sync.Mutex
+	match  engine.Match
+	state  engine.State
+	record *engine.Record
+	seats  [2]seat
+
+	// allowHands is whether spectators may see both hands
+	allowHands bool
+
+	// deal is the number of the current deal, counted from 1, or 0 before
+	// the first deal
+	deal int
+
+	// next holds which players asked for the next deal
+	next [2]bool
+
+	listener net.Listener
+	conns    map[*conn]bool
+	closed   bool
+}
+
+// seat is the place of a player at the table.
+type seat struct {
+	// token identifies the player when they reconnect; it is empty until
+	// a player takes the seat
+	token string
+
+	// conn is the connection of the player or nil while they are not
+	// connected; the messages in queue are sent to it on a separate
+	// goroutine
+	conn  *conn
+	queue chan message
+}
+
+// NewServer creates a server for a match played with the rules. The decks
+// are shuffled with the seed.
+func NewServer(rules engine.Rules, seed int64) *Server {
+	return &Server{
+		rules:      rules,
+		rng:        mathrand.New(mathrand.NewSource(seed)),
+		spectators: NewBroadcast(),
+		match:      engine.NewMatch(engine.PlayerOne, rules),
+		conns:      make(map[*conn]bool),
+	}
+}
+
+// AllowHands lets spectators watch the deals with both hands and the stack
+// shown. Anybody who can connect to the server can watch, the players
+// too, so it should only be allowed when they are trusted not to look.
+func (s *Server) AllowHands() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.allowHands = true
+}
+
+// ListenAndServe listens on the TCP address and serves the players that
+// connect to it until the server is closed.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve serves the players that connect to the listener until the server
+// is closed. It returns nil when the server is closed.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return nil
+	}
+	s.listener = l
+	s.mu.Unlock()
+
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		go s.serve(newConn(c))
+	}
+}
+
+// Close stops listening and closes the connections of the players and the
+// spectators.
+func (s *Server) Close() error {
+	s.spectators.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for c := range s.conns {
+		c.Close()
+	}
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+// serve reads the messages of a player until the connection is lost.
+func (s *Server) serve(c *conn) {
+	defer c.Close()
+
+	c.SetReadDeadline(time.Now().Add(helloTimeout))
+	hello, err := c.receive()
+	if err != nil {
+		return
+	}
+	if hello.Type != "hello" {
+		c.send(message{Type: "error", Error: fmt.Sprintf("expected hello, got %q", hello.Type)})
+		return
+	}
+	c.SetReadDeadline(time.Time{})
+
+	if hello.Spectate != "" {
+		s.mu.Lock()
+		allowHands := s.allowHands
+		s.mu.Unlock()
+		if hello.Spectate == SpectateHands && !allowHands {
+			c.send(message{Type: "error", Error: "the server does not show the hands to spectators"})
+			return
+		}
+		s.spectators.watch(c, hello.Spectate)
+		return
+	}
+	p, err := s.join(c, hello.Token)
+	if err != nil {
+		c.send(message{Type: "error", Error: err.Error()})
+		return
+	}
+	defer s.leave(p, c)
+
+	for {
+		m, err := c.receive()
+		if err != nil {
+			return
+		}
+		s.handle(p, m)
+	}
+}
+
+// join seats the player that connected or returns an error if they cannot
+// take a seat.
+func (s *Server) join(c *conn, token string) (engine.Player, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, fmt.Errorf("the server is closed")
+	}
+
+	p := engine.PlayerOne
+	switch {
+	case token == "" && s.seats[engine.PlayerOne].token == "":
+	case token == "" && s.seats[engine.PlayerTwo].token == "":
+		p = engine.PlayerTwo
+	case token == "":
+		return 0, fmt.Errorf("the game is full")
+	case token == s.seats[engine.PlayerTwo].token:
+		p = engine.PlayerTwo
+	case token != s.seats[engine.PlayerOne].token:
+		return 0, fmt.Errorf("unknown token")
+	}
+
+	seat := &s.seats[p]
+	if seat.conn != nil {
+		// the old connection of the player may not have noticed yet
+		// that it is lost
+		seat.conn.Close()
+		close(seat.queue)
+		delete(s.conns, seat.conn)
+	}
+	if seat.token == "" {
+		seat.token = newToken()
+		log.Printf("%v joined from %v", p, c.RemoteAddr())
+	} else {
+		log.Printf("%v reconnected from %v", p, c.RemoteAddr())
+	}
+	seat.conn = c
+	seat.queue = make(chan message, playerQueue)
+	go write(c, seat.queue)
+	s.conns[c] = true
+	s.send(p, message{Type: "welcome", Token: seat.token})
+
+	switch {
+	case s.deal == 0 && s.seats[p.Other()].token != "":
+		s.startDeal()
+	case s.deal != 0:
+		s.sendState(p, nil)
+	}
+	return p, nil
+}
+
+// leave frees the seat of a player whose connection was lost until they
+// reconnect.
+func (s *Server) leave(p engine.Player, c *conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conns, c)
+	if seat := &s.seats[p]; seat.conn == c {
+		close(seat.queue)
+		seat.conn, seat.queue = nil, nil
+		log.Printf("%v disconnected", p)
+	}
+}
+
+// handle plays the move of a player or starts the next deal.
+func (s *Server) handle(p engine.Player, m message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	switch m.Type {
+	case "move":
+		err = s.play(p, m)
+	case "next":
+		err = s.startNextDeal(p)
+	default:
+		err = fmt.Errorf("unknown message %q", m.Type)
+	}
+	if err != nil {
+		s.send(p, message{Type: "error", Error: err.Error()})
+		if s.deal != 0 {
+			s.sendState(p, nil)
+		}
+	}
+}
+
+func (s *Server) play(p engine.Player, m message) error {
+	switch {
+	case s.deal == 0 || m.Deal != s.deal:
+		return fmt.Errorf("deal %d is not being played", m.Deal)
+	case s.state.IsOver():
+		return engine.ErrGameOver
+	case s.state.ToMove() != p:
+		return fmt.Errorf("it is not your turn")
+	}
+
+	move, err := engine.ParseMove(m.Move)
+	if err != nil {
+		return err
+	}
+	state, events, err := s.state.Apply(move)
+	if err != nil {
+		return err
+	}
+	s.state = state
+	s.record.Add(move)
+	for _, q := range []engine.Player{engine.PlayerOne, engine.PlayerTwo} {
+		s.sendState(q, events)
+	}
+	s.spectators.Show(s.deal, s.record, events)
+	if state.IsOver() {
+		result := state.Result()
+		log.Printf("deal %d won by %v with %d game points (%d:%d)", s.deal, result.Winner, result.GamePoints,
+			result.Scores[engine.PlayerOne], result.Scores[engine.PlayerTwo])
+	}
+	return nil
+}
+
+// startNextDeal starts the next deal once both players asked for it.
+func (s *Server) startNextDeal(p engine.Player) error {
+	if s.deal == 0 || !s.state.IsOver() {
+		return fmt.Errorf("the deal is not over")
+	}
+	s.next[p] = true
+	if s.next[engine.PlayerOne] && s.next[engine.PlayerTwo] {
+		s.match.AddDeal(s.state)
+		s.startDeal()
+	}
+	return nil
+}
+
+// startDeal deals the cards for the next deal and shows it to the players.
+func (s *Server) startDeal() {
+	if s.match.IsOver() {
+		log.Printf("match won by %v", s.match.Winner())
+		s.match = engine.NewMatch(engine.PlayerOne, s.rules)
+	}
+	s.record = engine.NewRecord(engine.NewDeck(s.rng), s.match.First(), s.rules)
+	s.state = s.match.NewDeal(s.record.Deck)
+	s.deal++
+	s.next = [2]bool{}
+	log.Printf("deal %d: %v plays first", s.deal, s.state.ToMove())
+	for _, p := range []engine.Player{engine.PlayerOne, engine.PlayerTwo} {
+		s.sendState(p, nil)
+	}
+	s.spectators.Show(s.deal, s.record, nil)
+}
+
+// sendState sends the player the deal as they see it after the events.
+func (s *Server) sendState(p engine.Player, events []engine.Event) {
+	view := s.state.View(p)
+	s.send(p, message{Type: "state", Deal: s.deal, View: &view, Events: engine.ViewEvents(events, p)})
+}
+
+// send queues the message for the player if they are connected. A player
+// that is too slow to take it is disconnected.
+func (s *Server) send(p engine.Player, m message) {
+	seat := s.seats[p]
+	if seat.conn == nil {
+		return
+	}
+	select {
+	case seat.queue <- m:
+	default:
+		seat.conn.Close()
+	}
+}
+
+// write sends the messages in the queue to the connection until the queue
+// is closed. A connection that cannot be reached is closed.
+func write(c *conn, queue chan message) {
+	for m := range queue {
+		if err := c.send(m); err != nil {
+			c.Close()
+		}
+	}
+}
+
+// newToken returns a random token with which a player reconnects.
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,421 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// recordingListener keeps what the server writes to each connection in
+// the order the connections were accepted.
+type recordingListener struct {
+	net.Listener
+
+	mu    sync.Mutex
+	conns []*recordingConn
+}
+
+func (l *recordingListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	rc := &recordingConn{Conn: c}
+	l.mu.Lock()
+	l.conns = append(l.conns, rc)
+	l.mu.Unlock()
+	return rc, nil
+}
+
+// messages returns the messages written to the connection accepted i-th.
+func (l *recordingListener) messages(t *testing.T, i int) []message {
+	t.Helper()
+
+	l.mu.Lock()
+	c := l.conns[i]
+	l.mu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var messages []message
+	dec := json.NewDecoder(bytes.NewReader(c.written.Bytes()))
+	for dec.More() {
+		var m message
+		if err := dec.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, m)
+	}
+	return messages
+}
+
+type recordingConn struct {
+	net.Conn
+
+	mu      sync.Mutex
+	written bytes.Buffer
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	c.written.Write(b)
+	c.mu.Unlock()
+	return c.Conn.Write(b)
+}
+
+// firstCard returns the move of the player on turn that plays their
+// lowest legal card.
+func firstCard(s engine.State) engine.Move {
+	for _, card := range s.SortedHand(s.ToMove()) {
+		if s.IsCardLegal(card) {
+			return engine.Move{Card: card}
+		}
+	}
+	panic("no card to play")
+}
+
+func TestServerPlaysDeal(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl := &recordingListener{Listener: l}
+	server := NewServer(engine.Rules{}, 1)
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(rl) }()
+
+	// the clients join one after another, so the first one plays as
+	// PlayerOne
+	var clients [2]*Client
+	var deals [2]*Deal
+	var states [2]engine.State
+	for i := range clients {
+		c, err := Dial(l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		clients[i] = c
+	}
+	timeout := time.After(10 * time.Second)
+	for i, c := range clients {
+		select {
+		case deals[i] = <-c.Deals():
+			states[i] = deals[i].State()
+		case <-timeout:
+			t.Fatal("the deal did not start")
+		}
+	}
+	server.mu.Lock()
+	initial := server.state
+	server.mu.Unlock()
+
+	// each client sees itself as PlayerOne and plays when it is on turn
+	var waiting [2]bool
+	var moves []engine.Move
+	for !states[0].IsOver() || !states[1].IsOver() {
+		for i, d := range deals {
+			if !states[i].IsOver() && states[i].ToMove() == engine.PlayerOne && !waiting[i] {
+				m := firstCard(states[i])
+				d.Play(m)
+				moves = append(moves, m)
+				waiting[i] = true
+			}
+		}
+		select {
+		case u := <-deals[0].Updates():
+			states[0], waiting[0] = u.State, false
+		case u := <-deals[1].Updates():
+			states[1], waiting[1] = u.State, false
+		case <-timeout:
+			t.Fatal("the deal did not end")
+		}
+	}
+
+	server.mu.Lock()
+	final := server.state
+	server.mu.Unlock()
+	if !final.IsOver() {
+		t.Fatal("the deal is not over on the server")
+	}
+	for i, s := range states {
+		p := engine.Player(i)
+		if s.Score(engine.PlayerOne) != final.Score(p) || s.Score(engine.PlayerTwo) != final.Score(p.Other()) {
+			t.Errorf("%v sees the scores %d:%d, want %d:%d", p, s.Score(engine.PlayerOne), s.Score(engine.PlayerTwo),
+				final.Score(p), final.Score(p.Other()))
+		}
+	}
+
+	// the states of the deal after each move, which the players were sent
+	dealStates := []engine.State{initial}
+	for _, m := range moves {
+		s, _, err := dealStates[len(dealStates)-1].Apply(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dealStates = append(dealStates, s)
+	}
+
+	for _, p := range []engine.Player{engine.PlayerOne, engine.PlayerTwo} {
+		var views []message
+		for _, m := range rl.messages(t, int(p)) {
+			if m.Type == "state" {
+				views = append(views, m)
+			}
+		}
+		if len(views) != len(dealStates) {
+			t.Fatalf("%v got %d states, want %d", p, len(views), len(dealStates))
+		}
+
+		for i, m := range views {
+			s := dealStates[i]
+			if m.View == nil {
+				t.Fatalf("%v got state %d without a view", p, i)
+			}
+
+			opponent := s.Hand(p.Other())
+			for _, card := range m.View.Hand {
+				if opponent.HasCard(card) {
+					t.Errorf("%v got state %d with the card %v of the opponent", p, i, card)
+				}
+			}
+			if got, want := m.View.Hand, s.SortedHand(p); !reflect.DeepEqual(got, want) {
+				t.Errorf("%v got state %d with the hand %v, want %v", p, i, got, want)
+			}
+			for _, e := range m.Events {
+				if e, ok := e.(engine.CardDrawn); ok && e.Player != engine.PlayerOne {
+					t.Errorf("%v got state %d with the card %v drawn by the opponent", p, i, e.Card)
+				}
+			}
+		}
+	}
+
+	for _, c := range clients {
+		c.Close()
+	}
+	server.Close()
+	if err := <-served; err != nil {
+		t.Errorf("Serve() = %v, want nil after Close", err)
+	}
+}
+
+func TestServerShowsHandsOnlyWhenAllowed(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(engine.Rules{}, 1)
+	defer server.Close()
+	go server.Serve(l)
+	addr := l.Addr().String()
+
+	if c, err := Watch(addr, SpectatePublic); err != nil {
+		t.Errorf("Watch(%s) = %v, want no error", SpectatePublic, err)
+	} else {
+		c.Close()
+	}
+	if c, err := Watch(addr, SpectateHands); err == nil {
+		c.Close()
+		t.Errorf("Watch(%s) succeeded without AllowHands", SpectateHands)
+	}
+
+	server.AllowHands()
+	if c, err := Watch(addr, SpectateHands); err != nil {
+		t.Errorf("Watch(%s) = %v after AllowHands, want no error", SpectateHands, err)
+	} else {
+		c.Close()
+	}
+}
+
+func TestServerDisconnectsSlowPlayer(t *testing.T) {
+	server := NewServer(engine.Rules{}, 1)
+	local, remote := net.Pipe()
+	defer remote.Close()
+
+	// nothing writes the queued messages, as if the player never read
+	// them; sending must not block and the player is disconnected once
+	// the queue is full
+	server.mu.Lock()
+	server.seats[engine.PlayerOne] = seat{conn: newConn(local), queue: make(chan message, playerQueue)}
+	for i := 0; i <= playerQueue; i++ {
+		server.send(engine.PlayerOne, message{Type: "welcome"})
+	}
+	server.mu.Unlock()
+
+	remote.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := remote.Read(make([]byte, 1)); err == nil || isTimeout(err) {
+		t.Errorf("Read() = %v, want the connection closed", err)
+	}
+}
+
+func isTimeout(err error) bool {
+	e, ok := err.(net.Error)
+	return ok && e.Timeout()
+}
+
+func TestServerRejectsMessageBeforeFirstDeal(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl := &recordingListener{Listener: l}
+	server := NewServer(engine.Rules{}, 1)
+	defer server.Close()
+	go server.Serve(rl)
+
+	c, err := Dial(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.send(message{Type: "next"})
+
+	// the error is answered without a state, as there is no deal yet
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var types []string
+		for _, m := range rl.messages(t, 0) {
+			types = append(types, m.Type)
+		}
+		if len(types) == 2 {
+			if want := []string{"welcome", "error"}; !reflect.DeepEqual(types, want) {
+				t.Errorf("the player got %v, want %v", types, want)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the player got %v, want an error", types)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !c.Connected() {
+		t.Error("the client disconnected after the error")
+	}
+}
+
+func TestServerBringsBackReconnectedPlayer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl := &recordingListener{Listener: l}
+	server := NewServer(engine.Rules{}, 1)
+	defer server.Close()
+	go server.Serve(rl)
+
+	var clients [2]*Client
+	var deals [2]*Deal
+	var states [2]engine.State
+	for i := range clients {
+		c, err := Dial(l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		clients[i] = c
+	}
+	timeout := time.After(20 * time.Second)
+	for i, c := range clients {
+		select {
+		case deals[i] = <-c.Deals():
+			states[i] = deals[i].State()
+		case <-timeout:
+			t.Fatal("the deal did not start")
+		}
+	}
+
+	// play plays the lowest card of the player on turn until the deal is
+	// over or, when stop is set, until the first player is on turn after
+	// the moves
+	play := func(moves int, stop bool) {
+		t.Helper()
+		var waiting [2]bool
+		for !states[0].IsOver() || !states[1].IsOver() {
+			if stop && moves <= 0 && !waiting[0] && !waiting[1] && states[0].ToMove() == engine.PlayerOne {
+				return
+			}
+			for i, d := range deals {
+				if !states[i].IsOver() && states[i].ToMove() == engine.PlayerOne && !waiting[i] {
+					d.Play(firstCard(states[i]))
+					waiting[i] = true
+					moves--
+				}
+			}
+			select {
+			case u := <-deals[0].Updates():
+				states[0], waiting[0] = u.State, false
+			case u := <-deals[1].Updates():
+				states[1], waiting[1] = u.State, false
+			case <-timeout:
+				t.Fatal("the deal did not go on")
+			}
+		}
+	}
+	play(4, true)
+
+	// the first player loses the connection on their turn, so that
+	// nothing happens in the deal until they are back
+	clients[0].mu.Lock()
+	lost := clients[0].conn
+	clients[0].mu.Unlock()
+	lost.Close()
+
+	select {
+	case u := <-deals[0].Updates():
+		if len(u.Events) != 0 {
+			t.Errorf("the reconnected player got the events %v, want none", u.Events)
+		}
+		if got, want := u.State.SortedHand(engine.PlayerOne), states[0].SortedHand(engine.PlayerOne); !reflect.DeepEqual(got, want) {
+			t.Errorf("the reconnected player got the hand %v, want %v", got, want)
+		}
+		if u.State.ToMove() != engine.PlayerOne {
+			t.Error("the reconnected player is not on turn")
+		}
+		states[0] = u.State
+	case <-timeout:
+		t.Fatal("the player did not get the deal back")
+	}
+	select {
+	case d := <-clients[0].Deals():
+		t.Errorf("the reconnected player got deal %d as a new deal", d.Number())
+	default:
+	}
+
+	welcome := func(i int) message {
+		t.Helper()
+		if messages := rl.messages(t, i); len(messages) > 0 && messages[0].Type == "welcome" {
+			return messages[0]
+		}
+		t.Fatalf("connection %d was not welcomed", i)
+		return message{}
+	}
+	if got, want := welcome(2).Token, welcome(0).Token; got != want {
+		t.Errorf("the player reconnected with the token %q, want %q", got, want)
+	}
+
+	play(0, false)
+	server.mu.Lock()
+	final := server.state
+	server.mu.Unlock()
+	if !final.IsOver() {
+		t.Error("the deal is not over on the server")
+	}
+}
+
+func TestClientRejectsBadView(t *testing.T) {
+	// the view of a deal that has not been dealt, in which all the cards
+	// are hidden but neither the hands nor the stack hold any
+	var s engine.State
+	view := s.View(engine.PlayerOne)
+
+	d := &Deal{}
+	if _, err := d.show(message{Type: "state", View: &view}); err == nil {
+		t.Error("show() accepted a view whose hidden cards do not add up")
+	}
+}
@@ -0,0 +1,32 @@
+//go:build headless
+// +build headless
+
+package main
+
+import (
+	"log"
+
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/engine"
+	"github.com/nvlbg/santase-gui/network"
+)
+
+const noGUI = "this binary is built without the GUI (headless tag); use --ui=tui or one of the subcommands such as simulate"
+
+// runGUI reports that the GUI is not available. Binaries built with the
+// headless tag do not link ebiten, which needs a display already when it
+// is initialized, so that the subcommands can run on machines without one.
+func runGUI(opponentAgent santase.Agent, playerAgent *santase.Agent, opts settings) {
+	log.Fatal(noGUI)
+}
+
+// runReplay reports that the GUI is not available.
+func runReplay(record *engine.Record, opts settings) {
+	log.Fatal(noGUI)
+}
+
+// runNetworkGUI reports that the GUI is not available.
+func runNetworkGUI(client *network.Client, opts settings) {
+	log.Fatal(noGUI)
+}
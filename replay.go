@@ -0,0 +1,228 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/ebitenutil"
+	"github.com/hajimehoshi/ebiten/inpututil"
+	"github.com/hajimehoshi/ebiten/text"
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/engine"
+	"github.com/nvlbg/santase-gui/table"
+)
+
+// timelineX and timelineY are the position of the first box of the
+// timeline with which the user jumps to a trick of the replayed deal.
+const (
+	timelineX   = 20
+	timelineY   = 690
+	timelineBox = 40
+	timelineGap = 4
+)
+
+// replayPosition is a point of the deal the viewer can show - its start,
+// the end of a trick or its end if it ends between tricks.
+type replayPosition struct {
+	// moves is the number of moves played up to this point
+	moves int
+
+	// trick holds the cards of the trick that has just ended, led by
+	// leader, or nil if no trick has ended at this point
+	trick  *[2]santase.Card
+	leader engine.Player
+}
+
+// viewer shows a recorded deal and lets the user step through it trick by
+// trick.
+type viewer struct {
+	*resources
+	record    *engine.Record
+	positions []replayPosition
+	position  int
+	game      *game
+	window    *window
+}
+
+// newViewer creates a viewer of the recorded deal. It returns an error if
+// a move of the record is illegal.
+func newViewer(record *engine.Record, opts settings) (*viewer, error) {
+	v := &viewer{
+		resources: loadResources(),
+		record:    record,
+		positions: []replayPosition{{}},
+		window:    &window{width: opts.width, height: opts.height},
+	}
+
+	s := record.InitialState()
+	for i, m := range record.Moves {
+		leader := s.Leader()
+		next, events, err := s.Apply(m)
+		if err != nil {
+			return nil, fmt.Errorf("move %d (%s): %w", i+1, engine.FormatMove(m), err)
+		}
+		s = next
+
+		for _, e := range events {
+			if e, ok := e.(engine.TrickWon); ok {
+				cards := e.Cards
+				v.positions = append(v.positions, replayPosition{i + 1, &cards, leader})
+			}
+		}
+	}
+	if last := v.positions[len(v.positions)-1]; last.moves != len(record.Moves) {
+		v.positions = append(v.positions, replayPosition{moves: len(record.Moves)})
+	}
+
+	if err := v.show(0, true); err != nil {
+		return nil, err
+	}
+	v.game.animator = newAnimator(opts.animationSpeed)
+	return v, nil
+}
+
+// show shows the deal at the passed position.
+func (v *viewer) show(position int, debugMode bool) error {
+	pos := v.positions[position]
+	record := *v.record
+	record.Moves = record.Moves[:pos.moves]
+
+	deal, err := table.New(&record, [2]santase.Agent{})
+	if err != nil {
+		return err
+	}
+	g := NewGame(v.resources, deal)
+	g.replay = true
+	g.debugMode = debugMode
+	if pos.trick != nil {
+		g.deal.ShowTrick(*pos.trick, pos.leader)
+	}
+
+	// the cards move from where they were at the previous position
+	if v.game != nil {
+		v.game.deal.Close()
+		g.animator = v.game.animator
+	}
+	v.position = position
+	v.game = g
+	return nil
+}
+
+// timelineBoxAt returns the area of the box of the timeline for the
+// position.
+func (l layout) timelineBoxAt(position int) image.Rectangle {
+	x := timelineX + position*(timelineBox+timelineGap)
+	return image.Rect(l.left(x), l.bottom(timelineY), l.left(x+timelineBox), l.bottom(timelineY+timelineBox/2+4))
+}
+
+// selectedPosition returns the position the user chose with the keyboard
+// or the mouse in this frame or -1 if none.
+func (v *viewer) selectedPosition() int {
+	last := len(v.positions) - 1
+	selected := -1
+
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyRight) && v.position < last:
+		selected = v.position + 1
+	case inpututil.IsKeyJustPressed(ebiten.KeyLeft) && v.position > 0:
+		selected = v.position - 1
+	case inpututil.IsKeyJustPressed(ebiten.KeyHome):
+		selected = 0
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnd):
+		selected = last
+	}
+
+	digits := []ebiten.Key{ebiten.Key0, ebiten.Key1, ebiten.Key2, ebiten.Key3, ebiten.Key4,
+		ebiten.Key5, ebiten.Key6, ebiten.Key7, ebiten.Key8, ebiten.Key9}
+	for i, key := range digits {
+		if inpututil.IsKeyJustPressed(key) && i <= last {
+			selected = i
+		}
+	}
+
+	if p := v.pointer; p.Clicked() {
+		for i := range v.positions {
+			if image.Pt(p.X, p.Y).In(v.layout.timelineBoxAt(i)) {
+				selected = i
+			}
+		}
+	}
+
+	return selected
+}
+
+func (v *viewer) update(screen *ebiten.Image) error {
+	v.resize(screen)
+	v.pointer.update(v.layout)
+	l := v.layout
+
+	if position := v.selectedPosition(); position >= 0 && position != v.position {
+		if err := v.show(position, v.game.debugMode); err != nil {
+			return err
+		}
+	}
+
+	if err := v.game.update(screen); err != nil {
+		return err
+	}
+	if ebiten.IsDrawingSkipped() {
+		return nil
+	}
+
+	var title string
+	switch last := len(v.positions) - 1; {
+	case v.position == 0:
+		title = "Start of the deal"
+	case v.positions[v.position].trick == nil:
+		title = "End of the deal"
+	default:
+		title = fmt.Sprintf("Trick %d", v.position)
+		if v.positions[last].trick != nil {
+			title = fmt.Sprintf("Trick %d of %d", v.position, last)
+		}
+	}
+	text.Draw(screen, title, v.fontFace, l.left(20), l.top(40), color.White)
+	text.Draw(screen, "Left/Right: step, 0-9: jump, F12: both hands", v.fontFaceSmall, l.left(20), l.top(70), color.White)
+
+	if state := v.game.deal.State(); state.IsOver() && v.position == len(v.positions)-1 {
+		result := "You win"
+		if state.Winner() == engine.PlayerTwo {
+			result = "You lose"
+		}
+		result += fmt.Sprintf(" +%d", state.GamePoints())
+		text.Draw(screen, result, v.fontFace, l.right(760), l.middle(480), color.White)
+	}
+
+	for i := range v.positions {
+		box := l.timelineBoxAt(i)
+		fill := color.NRGBA{0x00, 0x66, 0x00, 0xff}
+		if i == v.position {
+			fill = color.NRGBA{0x00, 0x33, 0x00, 0xff}
+		}
+		ebitenutil.DrawRect(screen, float64(box.Min.X), float64(box.Min.Y),
+			float64(box.Dx()), float64(box.Dy()), fill)
+		label := fmt.Sprint(i)
+		labelX := box.Min.X + l.size(timelineBox/2) - len(label)*l.size(fontSizeSmall)/2
+		text.Draw(screen, label, v.fontFaceSmall, labelX, box.Max.Y-l.size(4), color.White)
+	}
+
+	return nil
+}
+
+// runReplay opens the window and shows the recorded deal.
+func runReplay(record *engine.Record, opts settings) {
+	v, err := newViewer(record, opts)
+	if err != nil {
+		log.Fatalf("cannot replay the deal: %v", err)
+	}
+	if err := v.window.run(v.update, "Santase - replay"); err != nil {
+		log.Fatal(err)
+	}
+}
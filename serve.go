@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/agents"
+	"github.com/nvlbg/santase-gui/api"
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// serve runs the serve subcommand which lets programs play deals against
+// an agent through an HTTP JSON API.
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", ":8080", "address to listen on for HTTP requests")
+	opponent := flags.String("opponent", "ismcts", "agent playing against the users, for example ismcts:c=5.4,budget=2s")
+	seed := flags.Int64("seed", 0, "seed for shuffling the cards; a random seed is used if 0")
+	autoClaim := flags.Bool("auto-claim", false, "end the deal as soon as a player collects 66 points")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of %s serve:\n", os.Args[0])
+		flags.PrintDefaults()
+		fmt.Fprintf(flags.Output(), "\nAvailable agents:\n%s", agents.Usage())
+	}
+	flags.Parse(args)
+
+	// validate the specification once so that errors are reported before
+	// the first game starts
+	agent, err := agents.New(*opponent)
+	if err != nil {
+		log.Fatalf("invalid --opponent: %v", err)
+	}
+	agents.Close(agent)
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("serving games against %s on http://%v (seed %d)", *opponent, l.Addr(), *seed)
+
+	server := api.NewServer(engine.Rules{AutoClaim: *autoClaim}, func() (santase.Agent, error) {
+		return agents.New(*opponent)
+	}, *seed)
+	defer server.Close()
+	if err := http.Serve(l, server); err != nil {
+		log.Fatal(err)
+	}
+}
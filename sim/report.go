@@ -0,0 +1,153 @@
+package sim
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// z95 is the quantile of the normal distribution for a two sided 95%
+// confidence interval.
+const z95 = 1.96
+
+// Estimate is a mean together with the half-width of its 95% confidence
+// interval. The margin is infinite when there are too few samples to
+// compute it.
+type Estimate struct {
+	Mean   float64
+	Margin float64
+}
+
+func (e Estimate) String() string {
+	if math.IsInf(e.Margin, 0) {
+		return fmt.Sprintf("%.3f", e.Mean)
+	}
+	return fmt.Sprintf("%.3f ± %.3f", e.Mean, e.Margin)
+}
+
+// estimate returns the mean of the samples and the margin of its 95%
+// confidence interval using the normal approximation.
+func estimate(samples []float64) Estimate {
+	n := float64(len(samples))
+	var sum float64
+	for _, x := range samples {
+		sum += x
+	}
+	mean := sum / n
+	if len(samples) < 2 {
+		return Estimate{mean, math.Inf(1)}
+	}
+
+	var squares float64
+	for _, x := range samples {
+		squares += (x - mean) * (x - mean)
+	}
+	variance := squares / (n - 1)
+	return Estimate{mean, z95 * math.Sqrt(variance/n)}
+}
+
+// Report holds the results of a simulation. The arrays are indexed by
+// agent in the order of Config.Agents.
+type Report struct {
+	// Matches tells whether the games are matches or single deals.
+	Matches bool
+
+	// Seeds is the number of seeds, Games is the number of deals or
+	// matches played (twice the seeds) and Deals is the total number of
+	// deals.
+	Seeds int
+	Games int
+	Deals int
+
+	// Wins is the number of deals or matches won.
+	Wins       [2]int
+	DealsWon   [2]int
+	Points     [2]int
+	GamePoints [2]int
+
+	// WinRate is the share of the games won by the first agent.
+	WinRate Estimate
+
+	// GamePointDifference is the average number of game points per deal
+	// the first agent collects more than the second one.
+	GamePointDifference Estimate
+}
+
+func newReport(matches bool, pairs []outcome) *Report {
+	var total outcome
+	winRates := make([]float64, len(pairs))
+	differences := make([]float64, len(pairs))
+	for i, pair := range pairs {
+		total.add(pair)
+		winRates[i] = float64(pair.wins[0]) / float64(pair.games)
+		differences[i] = float64(pair.gamePoints[0]-pair.gamePoints[1]) / float64(pair.deals)
+	}
+
+	return &Report{
+		Matches:             matches,
+		Seeds:               len(pairs),
+		Games:               total.games,
+		Deals:               total.deals,
+		Wins:                total.wins,
+		DealsWon:            total.dealsWon,
+		Points:              total.points,
+		GamePoints:          total.gamePoints,
+		WinRate:             estimate(winRates),
+		GamePointDifference: estimate(differences),
+	}
+}
+
+// perDeal returns the average of the value per deal.
+func (r *Report) perDeal(value int) float64 {
+	return float64(value) / float64(r.Deals)
+}
+
+// Print writes the report as a table with a column for each agent.
+func (r *Report) Print(w io.Writer, names [2]string) {
+	unit := "deals"
+	if r.Matches {
+		unit = "matches"
+	}
+
+	fmt.Fprintf(w, "player:   %s\n", names[0])
+	fmt.Fprintf(w, "opponent: %s\n", names[1])
+	fmt.Fprintf(w, "%d %s (%d seeds played from both seats)\n\n", r.Games, unit, r.Seeds)
+
+	row := func(label, player, opponent, note string) {
+		line := fmt.Sprintf("%-22s %10s %10s   %s", label, player, opponent, note)
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+	percent := func(x float64) string {
+		return fmt.Sprintf("%.2f%%", 100*x)
+	}
+
+	row("", "player", "opponent", "")
+	if r.Matches {
+		row("matches won", fmt.Sprint(r.Wins[0]), fmt.Sprint(r.Wins[1]), "")
+		row("deals won", fmt.Sprint(r.DealsWon[0]), fmt.Sprint(r.DealsWon[1]),
+			fmt.Sprintf("(%d deals)", r.Deals))
+	} else {
+		row("deals won", fmt.Sprint(r.Wins[0]), fmt.Sprint(r.Wins[1]), "")
+	}
+
+	margin := ""
+	if !math.IsInf(r.WinRate.Margin, 0) {
+		margin = fmt.Sprintf("(95%% CI ± %s)", percent(r.WinRate.Margin))
+	}
+	row("win rate", percent(r.WinRate.Mean), percent(1-r.WinRate.Mean), margin)
+
+	row("points per deal",
+		fmt.Sprintf("%.1f", r.perDeal(r.Points[0])),
+		fmt.Sprintf("%.1f", r.perDeal(r.Points[1])), "")
+	row("game points per deal",
+		fmt.Sprintf("%.3f", r.perDeal(r.GamePoints[0])),
+		fmt.Sprintf("%.3f", r.perDeal(r.GamePoints[1])),
+		fmt.Sprintf("(difference %s)", r.GamePointDifference))
+	if r.Matches {
+		perMatch := func(value int) string {
+			return fmt.Sprintf("%.2f", float64(value)/float64(r.Games))
+		}
+		row("game points per match", perMatch(r.GamePoints[0]), perMatch(r.GamePoints[1]), "")
+	}
+}
@@ -0,0 +1,225 @@
+// Package sim plays many deals or matches between two agents without a
+// user interface and collects statistics about the results.
+//
+// Every seed is played twice with the agents swapping seats, so both
+// agents get exactly the same cards. This paired comparison removes most
+// of the luck of the deal from the results and the confidence intervals
+// are computed over the pairs.
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/agents"
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// AgentFactory creates a new instance of an agent. A new agent is created
+// for every deal or match, so agents are never shared between goroutines.
+type AgentFactory func() (santase.Agent, error)
+
+// Config describes a simulation between two agents.
+type Config struct {
+	// Agents create the two agents being compared.
+	Agents [2]AgentFactory
+
+	// Seeds is the number of seeds to play. Every seed is played twice,
+	// once from each seat.
+	Seeds int
+
+	// Matches makes every seed a whole match to GamePointsToWin instead
+	// of a single deal.
+	Matches bool
+
+	// Seed is the seed of the first deal or match; the following ones use
+	// the next seeds.
+	Seed int64
+
+	// Workers is the number of deals or matches played in parallel. If
+	// not positive, the number of CPU cores is used. Agents that think for
+	// a fixed time per move use all cores themselves and are weaker than
+	// in real play when they run in parallel.
+	Workers int
+
+	Rules engine.Rules
+
+	// Progress, if not nil, is called after every finished seed with the
+	// number of finished seeds.
+	Progress func(done, total int)
+}
+
+// outcome holds the results of the agents (not of the seats) in some
+// number of deals or matches.
+type outcome struct {
+	games      int
+	deals      int
+	wins       [2]int
+	dealsWon   [2]int
+	points     [2]int
+	gamePoints [2]int
+}
+
+func (o *outcome) add(other outcome) {
+	o.games += other.games
+	o.deals += other.deals
+	for i := 0; i < 2; i++ {
+		o.wins[i] += other.wins[i]
+		o.dealsWon[i] += other.dealsWon[i]
+		o.points[i] += other.points[i]
+		o.gamePoints[i] += other.gamePoints[i]
+	}
+}
+
+// addDeal records the result of a deal; seats maps the players to the
+// indexes of the agents.
+func (o *outcome) addDeal(result engine.DealResult, seats [2]int) {
+	o.deals++
+	o.dealsWon[seats[result.Winner]]++
+	o.gamePoints[seats[result.Winner]] += result.GamePoints
+	for p := engine.PlayerOne; p <= engine.PlayerTwo; p++ {
+		o.points[seats[p]] += result.Scores[p]
+	}
+}
+
+// Run plays the simulation and returns its results. It stops at the first
+// error, for example when an agent chooses an illegal move.
+func Run(cfg Config) (*Report, error) {
+	if cfg.Seeds <= 0 {
+		return nil, fmt.Errorf("the number of seeds must be positive")
+	}
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	pairs := make([]outcome, cfg.Seeds)
+	jobs := make(chan int)
+	finished := make(chan error)
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var err error
+				pairs[i], err = cfg.playPair(cfg.Seed + int64(i))
+				if err != nil {
+					err = fmt.Errorf("seed %d: %w", cfg.Seed+int64(i), err)
+				}
+				finished <- err
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for i := 0; i < cfg.Seeds; i++ {
+			select {
+			case jobs <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var err error
+	for i := 0; i < cfg.Seeds && err == nil; i++ {
+		err = <-finished
+		if err == nil && cfg.Progress != nil {
+			cfg.Progress(i+1, cfg.Seeds)
+		}
+	}
+	close(done)
+
+	// let the busy workers finish so that no goroutine is left behind
+	go func() {
+		for range finished {
+		}
+	}()
+	wg.Wait()
+	close(finished)
+
+	if err != nil {
+		return nil, err
+	}
+	return newReport(cfg.Matches, pairs), nil
+}
+
+// playPair plays the seed twice with the agents swapping seats.
+func (cfg *Config) playPair(seed int64) (outcome, error) {
+	var pair outcome
+	for _, seats := range [2][2]int{{0, 1}, {1, 0}} {
+		var o outcome
+		var err error
+		if cfg.Matches {
+			o, err = cfg.playMatch(seed, seats)
+		} else {
+			o, err = cfg.playDeal(seed, seats)
+		}
+		if err != nil {
+			return outcome{}, err
+		}
+		pair.add(o)
+	}
+	return pair, nil
+}
+
+// newAgents creates the agents sitting in the seats, indexed by player.
+func (cfg *Config) newAgents(seats [2]int) ([2]santase.Agent, error) {
+	var result [2]santase.Agent
+	for p, i := range seats {
+		agent, err := cfg.Agents[i]()
+		if err != nil {
+			return result, err
+		}
+		result[p] = agent
+	}
+	return result, nil
+}
+
+func (cfg *Config) playDeal(seed int64, seats [2]int) (outcome, error) {
+	deck := engine.NewDeck(rand.New(rand.NewSource(seed)))
+	players, err := cfg.newAgents(seats)
+	if err != nil {
+		return outcome{}, err
+	}
+	defer agents.Close(players[:]...)
+
+	s, err := engine.PlayOut(engine.NewState(deck, engine.PlayerOne, cfg.Rules), players)
+	if err != nil {
+		return outcome{}, err
+	}
+
+	o := outcome{games: 1}
+	o.addDeal(s.Result(), seats)
+	o.wins = o.dealsWon
+	return o, nil
+}
+
+func (cfg *Config) playMatch(seed int64, seats [2]int) (outcome, error) {
+	rng := rand.New(rand.NewSource(seed))
+	players, err := cfg.newAgents(seats)
+	if err != nil {
+		return outcome{}, err
+	}
+	defer agents.Close(players[:]...)
+
+	o := outcome{games: 1}
+	match := engine.NewMatch(engine.PlayerOne, cfg.Rules)
+	for !match.IsOver() {
+		s, err := engine.PlayOut(match.NewDeal(engine.NewDeck(rng)), players)
+		if err != nil {
+			return outcome{}, err
+		}
+		o.addDeal(match.AddDeal(s), seats)
+	}
+	o.wins[seats[match.Winner()]]++
+	return o, nil
+}
@@ -0,0 +1,137 @@
+package sim
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// cardAgent always plays its lowest legal card, or its highest one if
+// highest is set, so the deals it plays depend only on the cards.
+type cardAgent struct {
+	highest bool
+}
+
+func (a cardAgent) GetMove(game *santase.Game) santase.Move {
+	hand := game.GetHand()
+	if played := game.GetCardPlayed(); played != nil && (game.IsClosed() || game.GetTrumpCard() == nil) {
+		hand = hand.GetValidResponses(*played, game.GetTrump())
+	}
+
+	cards := hand.ToSlice()
+	sort.Slice(cards, func(i, j int) bool {
+		if cards[i].Suit != cards[j].Suit {
+			return cards[i].Suit < cards[j].Suit
+		}
+		return cards[i].Rank < cards[j].Rank
+	})
+	if a.highest {
+		return santase.Move{Card: cards[len(cards)-1]}
+	}
+	return santase.Move{Card: cards[0]}
+}
+
+func newCardAgent(highest bool) AgentFactory {
+	return func() (santase.Agent, error) {
+		return cardAgent{highest}, nil
+	}
+}
+
+func TestRunDeals(t *testing.T) {
+	cfg := Config{
+		Agents:  [2]AgentFactory{newCardAgent(false), newCardAgent(true)},
+		Seeds:   50,
+		Seed:    100,
+		Workers: 4,
+	}
+	report, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// play the same deals one by one
+	var wins, points, gamePoints [2]int
+	for seed := cfg.Seed; seed < cfg.Seed+int64(cfg.Seeds); seed++ {
+		for _, seats := range [2][2]int{{0, 1}, {1, 0}} {
+			players := [2]santase.Agent{cardAgent{seats[0] == 1}, cardAgent{seats[1] == 1}}
+			deck := engine.NewDeck(rand.New(rand.NewSource(seed)))
+			s, err := engine.PlayOut(engine.NewState(deck, engine.PlayerOne, engine.Rules{}), players)
+			if err != nil {
+				t.Fatalf("seed %d: %v", seed, err)
+			}
+			wins[seats[s.Winner()]]++
+			gamePoints[seats[s.Winner()]] += s.GamePoints()
+			for p := engine.PlayerOne; p <= engine.PlayerTwo; p++ {
+				points[seats[p]] += s.Score(p)
+			}
+		}
+	}
+
+	if report.Seeds != cfg.Seeds || report.Games != 2*cfg.Seeds || report.Deals != 2*cfg.Seeds {
+		t.Errorf("report of %d seeds, %d games and %d deals, want %d, %d and %d",
+			report.Seeds, report.Games, report.Deals, cfg.Seeds, 2*cfg.Seeds, 2*cfg.Seeds)
+	}
+	if report.Wins != wins || report.DealsWon != wins {
+		t.Errorf("Wins = %v, DealsWon = %v, want %v", report.Wins, report.DealsWon, wins)
+	}
+	if report.GamePoints != gamePoints {
+		t.Errorf("GamePoints = %v, want %v", report.GamePoints, gamePoints)
+	}
+	if report.Points != points {
+		t.Errorf("Points = %v, want %v", report.Points, points)
+	}
+	if want := float64(wins[0]) / float64(report.Games); report.WinRate.Mean != want {
+		t.Errorf("WinRate.Mean = %v, want %v", report.WinRate.Mean, want)
+	}
+}
+
+func TestRunSameAgents(t *testing.T) {
+	// the same agent wins the same seat of every seed, so each of the two
+	// copies wins one deal of every pair
+	report, err := Run(Config{
+		Agents: [2]AgentFactory{newCardAgent(false), newCardAgent(false)},
+		Seeds:  30,
+		Seed:   1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Wins != [2]int{30, 30} {
+		t.Errorf("Wins = %v, want 30 each", report.Wins)
+	}
+	if report.GamePoints[0] != report.GamePoints[1] {
+		t.Errorf("GamePoints = %v, want them equal", report.GamePoints)
+	}
+	if report.WinRate != (Estimate{0.5, 0}) {
+		t.Errorf("WinRate = %v, want 0.500 ± 0.000", report.WinRate)
+	}
+}
+
+func TestRunMatches(t *testing.T) {
+	report, err := Run(Config{
+		Agents:  [2]AgentFactory{newCardAgent(false), newCardAgent(true)},
+		Seeds:   5,
+		Seed:    7,
+		Matches: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Games != 10 || report.Wins[0]+report.Wins[1] != 10 {
+		t.Errorf("%v matches won of %d, want 10 of 10", report.Wins, report.Games)
+	}
+	if report.DealsWon[0]+report.DealsWon[1] != report.Deals {
+		t.Errorf("%v deals won of %d", report.DealsWon, report.Deals)
+	}
+	for i, gamePoints := range report.GamePoints {
+		if gamePoints < engine.GamePointsToWin*report.Wins[i] {
+			t.Errorf("agent %d won %d matches with %d game points", i, report.Wins[i], gamePoints)
+		}
+	}
+}
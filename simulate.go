@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/agents"
+	"github.com/nvlbg/santase-gui/engine"
+	"github.com/nvlbg/santase-gui/sim"
+)
+
+// simulate runs the simulate subcommand which plays agents against each
+// other without opening a window and prints statistics about the results.
+func simulate(args []string) {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	player := flags.String("player", "ismcts", "agent being evaluated")
+	opponent := flags.String("opponent", "random", "agent the player is compared against")
+	seeds := flags.Int("n", 100, "number of seeds; each is played twice with the agents swapping seats")
+	matches := flags.Bool("matches", false, "play a whole match to 11 game points for every seed instead of a single deal")
+	seed := flags.Int64("seed", 1, "seed of the first deal; the following deals use the next seeds")
+	workers := flags.Int("workers", 0, "number of deals played in parallel (default the number of CPU cores, "+
+		"or 1 if an agent thinks for a fixed time per move, like ismcts, which uses all cores itself)")
+	autoClaim := flags.Bool("auto-claim", false, "end the deal as soon as a player collects 66 points")
+	quiet := flags.Bool("q", false, "do not report progress")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of %s simulate:\n", os.Args[0])
+		flags.PrintDefaults()
+		fmt.Fprintf(flags.Output(), "\nAvailable agents:\n%s", agents.Usage())
+	}
+	flags.Parse(args)
+
+	// validate the specifications once so that errors are reported before
+	// the simulation starts
+	specs := [2]string{*player, *opponent}
+	if *workers <= 0 {
+		*workers = runtime.NumCPU()
+		if agents.IsTimed(*player) || agents.IsTimed(*opponent) {
+			*workers = 1
+		}
+	}
+	var factories [2]sim.AgentFactory
+	for i, spec := range specs {
+		agent, err := agents.New(spec)
+		if err != nil {
+			log.Fatalf("invalid agent: %v", err)
+		}
+		agents.Close(agent)
+		spec := spec
+		factories[i] = func() (santase.Agent, error) {
+			return agents.New(spec)
+		}
+	}
+
+	cfg := sim.Config{
+		Agents:  factories,
+		Seeds:   *seeds,
+		Matches: *matches,
+		Seed:    *seed,
+		Workers: *workers,
+		Rules:   engine.Rules{AutoClaim: *autoClaim},
+	}
+	if !*quiet {
+		cfg.Progress = func(done, total int) {
+			fmt.Fprintf(os.Stderr, "\r%d/%d seeds", done, total)
+			if done == total {
+				fmt.Fprintln(os.Stderr)
+			}
+		}
+	}
+
+	report, err := sim.Run(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report.Print(os.Stdout, specs)
+}
@@ -0,0 +1,327 @@
+package table
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// Spectators are shown the deals of a match as they are played, for
+// example over a network.
+type Spectators interface {
+	// Show shows the deal with the passed number in the series after the
+	// events, or when it starts if there are none. The record holds the
+	// moves played so far.
+	Show(deal int, record *engine.Record, events []engine.Event)
+}
+
+// MatchOptions are the settings of a match played against an agent.
+type MatchOptions struct {
+	Rules engine.Rules
+
+	// Seed is used to shuffle the cards for the deals of the match
+	Seed int64
+
+	// Deck is the deck of the first deal; if nil it is shuffled using the
+	// seed like the decks of the other deals
+	Deck []santase.Card
+
+	// Record is a saved deal which is continued as the first deal
+	Record *engine.Record
+
+	// RecordsDir is the directory the deals are saved to
+	RecordsDir string
+
+	// Spectators are shown the deals of the match, if not nil
+	Spectators Spectators
+}
+
+// errRemoteMatch is returned when a deal of a match played over a network
+// is to be dealt, replayed or saved by the user.
+var errRemoteMatch = errors.New("the deals of a match played over a network come from the server")
+
+// Match chains the deals of a match and keeps track of the game points
+// independently of how they are shown. The front end advances the current
+// deal and calls Update after every frame, so that the result of the deal
+// is recorded once it is over, and then shows the scoreboard until the
+// user starts the next deal. Like a Deal, a match is used from a single
+// goroutine.
+//
+// The deals of a match played over a network are started by its server
+// and cannot be replayed, saved or abandoned.
+type Match struct {
+	opts     MatchOptions
+	agents   [2]santase.Agent
+	remote   bool
+	state    engine.Match
+	deal     *Deal
+	rng      *rand.Rand
+	deck     []santase.Card
+	first    engine.Player
+	counted  bool
+	lastDeal engine.DealResult
+	recorded bool
+
+	// dealNumber counts the deals shown to the spectators
+	dealNumber int
+}
+
+// NewMatch creates a match against an agent and starts its first deal. The
+// agents are indexed by player and a nil agent means that the player is
+// the user.
+func NewMatch(opts MatchOptions, agents [2]santase.Agent) (*Match, error) {
+	first := engine.PlayerOne
+	if opts.Record != nil {
+		first = opts.Record.First
+	}
+
+	m := &Match{
+		opts:   opts,
+		agents: agents,
+		state:  engine.NewMatch(first, opts.Rules),
+		rng:    rand.New(rand.NewSource(opts.Seed)),
+	}
+	if err := m.NextDeal(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// NewRemoteMatch creates a match played or watched over a network. It has
+// no deal until the first one is started with StartRemote.
+func NewRemoteMatch(rules engine.Rules) *Match {
+	return &Match{
+		opts:   MatchOptions{Rules: rules},
+		remote: true,
+		state:  engine.NewMatch(engine.PlayerOne, rules),
+	}
+}
+
+// Deal returns the current deal, or nil if a match played over a network
+// has not started yet.
+func (m *Match) Deal() *Deal {
+	return m.deal
+}
+
+// State returns the deals of the match played so far and its game points.
+func (m *Match) State() engine.Match {
+	return m.state
+}
+
+// Seed returns the seed the decks of the match are shuffled with.
+func (m *Match) Seed() int64 {
+	return m.opts.Seed
+}
+
+// IsRemote returns whether the match is played or watched over a network.
+func (m *Match) IsRemote() bool {
+	return m.remote
+}
+
+// Counted returns whether the result of the current deal counts towards
+// the match; it does not for a replayed deal.
+func (m *Match) Counted() bool {
+	return m.counted
+}
+
+// Result returns the result of the current deal and whether it is over and
+// has been recorded by Update.
+func (m *Match) Result() (engine.DealResult, bool) {
+	return m.lastDeal, m.recorded
+}
+
+// NextDeal deals the cards for the next deal of the match and starts it. A
+// new match is started after one that is over.
+func (m *Match) NextDeal() error {
+	if m.remote {
+		return errRemoteMatch
+	}
+	if m.state.IsOver() {
+		m.state = engine.NewMatch(engine.PlayerOne, m.opts.Rules)
+	}
+
+	var record *engine.Record
+	switch {
+	case m.deal == nil && m.opts.Record != nil:
+		record = m.opts.Record
+	case m.deal == nil && m.opts.Deck != nil:
+		record = engine.NewRecord(m.opts.Deck, m.state.First(), m.opts.Rules)
+	default:
+		record = engine.NewRecord(engine.NewDeck(m.rng), m.state.First(), m.opts.Rules)
+	}
+	m.deck = record.Deck
+	m.first = record.First
+	m.counted = true
+	return m.startDeal(record)
+}
+
+// NewGame abandons the match and starts a new one.
+func (m *Match) NewGame() error {
+	if m.remote {
+		return errRemoteMatch
+	}
+	m.state = engine.NewMatch(engine.PlayerOne, m.opts.Rules)
+	return m.NextDeal()
+}
+
+// ReplayDeal starts the current deal again with the same cards. The deal
+// is replayed for practice and its result does not count towards the
+// match, even if it was replayed before it ended: the player has seen
+// some of the cards by then.
+func (m *Match) ReplayDeal() error {
+	if m.remote {
+		return errRemoteMatch
+	}
+	m.counted = false
+	return m.startDeal(engine.NewRecord(m.deck, m.first, m.opts.Rules))
+}
+
+func (m *Match) startDeal(record *engine.Record) error {
+	log.Printf("deal %d (seed %d): --deal=%s", len(m.state.Deals())+1, m.opts.Seed, engine.FormatDeck(m.deck))
+
+	deal, err := New(record, m.agents)
+	if err != nil {
+		return err
+	}
+	if m.deal != nil {
+		m.deal.Close()
+	}
+	m.deal = deal
+	m.recorded = false
+
+	if m.opts.Spectators != nil {
+		m.dealNumber++
+		deal.ShowTo(m.opts.Spectators, m.dealNumber)
+	}
+	return nil
+}
+
+// StartRemote starts a deal of a match played over a network, which
+// replaces the current deal. A new match is started after one that is
+// over.
+func (m *Match) StartRemote(deal *Deal) {
+	if m.deal != nil {
+		m.deal.Close()
+	}
+	if m.state.IsOver() {
+		m.state = engine.NewMatch(engine.PlayerOne, m.opts.Rules)
+	}
+	m.deal = deal
+	m.counted = true
+	m.recorded = false
+}
+
+// Update records the result of the current deal once it is over and
+// returns whether it has been recorded. The deal is saved to the records
+// directory, except in a match played over a network, and its result is
+// added to the match if it counts.
+func (m *Match) Update() bool {
+	if m.deal == nil || !m.deal.IsOver() || m.recorded {
+		return m.recorded
+	}
+
+	if !m.remote {
+		if name, err := m.Save(); err != nil {
+			log.Printf("cannot save the game: %v", err)
+		} else {
+			log.Printf("game saved to %s", name)
+		}
+	}
+	if m.counted {
+		m.lastDeal = m.state.AddDeal(m.deal.State())
+	} else {
+		m.lastDeal = m.deal.State().Result()
+	}
+	m.recorded = true
+	return true
+}
+
+// Save writes the record of the current deal to a new file in the records
+// directory and returns the name of the file.
+func (m *Match) Save() (string, error) {
+	if m.remote {
+		return "", errRemoteMatch
+	}
+	return WriteRecord(m.opts.RecordsDir, m.deal.Record())
+}
+
+// Close abandons the current deal.
+func (m *Match) Close() {
+	if m.deal != nil {
+		m.deal.Close()
+	}
+}
+
+// ScoreboardRow is a deal of a match as it is listed on the scoreboard.
+type ScoreboardRow struct {
+	// Deal is the number of the deal in the match, starting from 1
+	Deal int
+
+	// Scores are the points of the players in the deal and GamePoints the
+	// game points they won with it, indexed by player
+	Scores     [2]int
+	GamePoints [2]int
+}
+
+// Scoreboard returns the rows of the scoreboard for at most the last max
+// deals of the match, or for all of them if max is 0.
+func (m *Match) Scoreboard(max int) []ScoreboardRow {
+	deals := m.state.Deals()
+	first := 0
+	if max > 0 && len(deals) > max {
+		first = len(deals) - max
+	}
+
+	var rows []ScoreboardRow
+	for i := first; i < len(deals); i++ {
+		row := ScoreboardRow{Deal: i + 1, Scores: deals[i].Scores}
+		row.GamePoints[deals[i].Winner] = deals[i].GamePoints
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// WriteRecord writes the record of a deal to a new file in the directory
+// and returns the name of the file. The file is named after the time it is
+// written; a number is added to the name of a deal saved in the same
+// second as another one, so that no file is overwritten.
+func WriteRecord(dir string, record *engine.Record) (string, error) {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	base := filepath.Join(dir, "deal-"+time.Now().Format("20060102-150405"))
+	name := base + ".json"
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	for i := 2; os.IsExist(err); i++ {
+		name = fmt.Sprintf("%s-%d.json", base, i)
+		f, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		os.Remove(name)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(name)
+		return "", err
+	}
+	return name, nil
+}
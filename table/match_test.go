@@ -0,0 +1,151 @@
+package table
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	santase "github.com/nvlbg/santase-ai"
+	"github.com/nvlbg/santase-ai/agents/random"
+
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// showing records the deals shown to the spectators.
+type showing struct {
+	deals  []int
+	events int
+}
+
+func (s *showing) Show(deal int, record *engine.Record, events []engine.Event) {
+	s.deals = append(s.deals, deal)
+	s.events += len(events)
+}
+
+func TestMatchCountsDeals(t *testing.T) {
+	dir := t.TempDir()
+	spectators := new(showing)
+	m, err := NewMatch(MatchOptions{Seed: 1, RecordsDir: dir, Spectators: spectators},
+		[2]santase.Agent{random.NewAgent(), random.NewAgent()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	for deal := 1; deal <= 2; deal++ {
+		if m.Update() {
+			t.Fatalf("deal %d is recorded before it is played", deal)
+		}
+		playOut(t, m.Deal())
+		if !m.Update() {
+			t.Fatalf("deal %d is not recorded after it is over", deal)
+		}
+		if result, _ := m.Result(); result != m.Deal().State().Result() {
+			t.Errorf("deal %d has the result %+v, want %+v", deal, result, m.Deal().State().Result())
+		}
+		if deal == 1 {
+			if err := m.NextDeal(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// a deal replayed after it is over does not count
+	deck := m.Deal().Record().Deck
+	if err := m.ReplayDeal(); err != nil {
+		t.Fatal(err)
+	}
+	if m.Counted() {
+		t.Error("a replayed deal counts towards the match")
+	}
+	if got, want := engine.FormatDeck(m.Deal().Record().Deck), engine.FormatDeck(deck); got != want {
+		t.Errorf("the deal is replayed with %s, want %s", got, want)
+	}
+	playOut(t, m.Deal())
+	m.Update()
+
+	state := m.State()
+	deals := state.Deals()
+	rows := m.Scoreboard(0)
+	if len(rows) != 2 || len(deals) != 2 {
+		t.Fatalf("the scoreboard has %d rows for %d deals, want 2", len(rows), len(deals))
+	}
+	for i, row := range rows {
+		deal := deals[i]
+		if row.Deal != i+1 || row.Scores != deal.Scores || row.GamePoints[deal.Winner] != deal.GamePoints ||
+			row.GamePoints[deal.Winner.Other()] != 0 {
+			t.Errorf("row %d is %+v for the deal %+v", i, row, deal)
+		}
+	}
+	if rows := m.Scoreboard(1); len(rows) != 1 || rows[0].Deal != 2 {
+		t.Errorf("the scoreboard of the last deal is %+v", rows)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("%d deals are saved, want 3", len(files))
+	}
+	for _, f := range files {
+		if filepath.Ext(f.Name()) != ".json" {
+			t.Errorf("the deal is saved to %s", f.Name())
+		}
+	}
+
+	if len(spectators.deals) == 0 || spectators.deals[len(spectators.deals)-1] != 3 || spectators.events == 0 {
+		t.Errorf("the spectators are shown the deals %v with %d events, want 3 deals", spectators.deals, spectators.events)
+	}
+}
+
+func TestMatchDoesNotCountDealReplayedBeforeItEnds(t *testing.T) {
+	m, err := NewMatch(MatchOptions{Seed: 1, RecordsDir: t.TempDir()},
+		[2]santase.Agent{random.NewAgent(), random.NewAgent()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if !m.Counted() {
+		t.Fatal("a new deal does not count towards the match")
+	}
+	if err := m.ReplayDeal(); err != nil {
+		t.Fatal(err)
+	}
+	if m.Counted() {
+		t.Error("a deal replayed before it ended counts towards the match")
+	}
+	playOut(t, m.Deal())
+	m.Update()
+	state := m.State()
+	if deals := state.Deals(); len(deals) != 0 {
+		t.Errorf("the match has %d deals after a replayed one, want 0", len(deals))
+	}
+
+	if err := m.NextDeal(); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Counted() {
+		t.Error("the deal after a replayed one does not count towards the match")
+	}
+}
+
+func TestRemoteMatchDealsFromServer(t *testing.T) {
+	m := NewRemoteMatch(engine.Rules{})
+	if m.Deal() != nil || m.Update() {
+		t.Fatal("a match played over a network has a deal before the server starts one")
+	}
+	for name, start := range map[string]func() error{
+		"NextDeal":   m.NextDeal,
+		"NewGame":    m.NewGame,
+		"ReplayDeal": m.ReplayDeal,
+	} {
+		if err := start(); err == nil {
+			t.Errorf("%s() of a match played over a network = nil, want an error", name)
+		}
+	}
+	if _, err := m.Save(); err == nil {
+		t.Error("Save() of a match played over a network = nil, want an error")
+	}
+}
@@ -0,0 +1,614 @@
+// Package table runs a deal between the user and agents independently of
+// how it is shown.
+//
+// A Deal owns the state of the deal and is advanced one frame at a time by
+// its front end, which makes all calls to it from a single goroutine. The
+// agents choose their moves on separate goroutines and the moves are
+// delivered back to the deal as messages, so the state never changes while
+// the front end is drawing it. Pauses, for example after a trick, are
+// counted in frames.
+//
+// A deal can also be played over a network against a Remote that holds the
+// real state of the deal. The deal then only shows what the user can see
+// and sends the moves of the user to the remote, or it shows a deal that
+// the user only watches.
+//
+// A Match chains the deals of a match and keeps its score, so that the
+// front ends only show the deals and the scoreboard between them.
+package table
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// FramesPerSecond is the rate at which front ends are expected to call
+// Update.
+const FramesPerSecond = 60
+
+// PauseFrames is the number of frames the deal waits after a trick is
+// completed and after an agent switches the trump card or closes the game,
+// so that the user can see what happened.
+const PauseFrames = 2 * FramesPerSecond
+
+// agentMove is the message with which an agent delivers its move.
+type agentMove struct {
+	move engine.Move
+	err  error
+}
+
+// Remote is the other side of a deal played over a network. It holds the
+// real state of the deal, in which the user is PlayerOne and their
+// opponent PlayerTwo, and plays the moves of the opponent.
+type Remote interface {
+	// Play sends the move of the user. It does not block; a move that
+	// cannot be sent is dropped and the next update shows the deal as it
+	// is.
+	Play(m engine.Move)
+
+	// Updates delivers the deal after every move of either player.
+	Updates() <-chan Update
+}
+
+// Update is the deal as the user sees it after a move played over a
+// network. The cards the user cannot see are replaced by other cards, so
+// that the state can be shown but does not reveal them.
+type Update struct {
+	State engine.State
+
+	// Events are the events of the move as the user sees them. They are
+	// nil when the update only brings the deal up to date, for example
+	// after the connection was restored.
+	Events []engine.Event
+
+	// Revealed maps the cards that stood for hidden cards and have turned
+	// out to be other cards, for example one the opponent played, to the
+	// cards they turned out to be.
+	Revealed map[santase.Card]santase.Card
+}
+
+// Deal is a deal in progress together with what is shown on the table.
+type Deal struct {
+	state  engine.State
+	record *engine.Record
+	agents [2]santase.Agent
+	views  [2]*santase.Game
+
+	// remote holds the real state of a deal played over a network; the
+	// user is waiting for it to answer their move and revealed holds the
+	// cards revealed with the last update. A spectator only watches the
+	// moves of both players.
+	remote    Remote
+	spectator bool
+	waiting   bool
+	revealed  map[santase.Card]santase.Card
+
+	// the cards on the table and the player that led the first of them
+	cardPlayed *santase.Card
+	response   *santase.Card
+	leader     engine.Player
+
+	// declarations of the player on turn that are not played yet
+	switchTrumpCard bool
+	closeGame       bool
+	announce        bool
+
+	// points of the marriage announced with the last move, if any
+	announcement int
+
+	// frames left until the end of the pause and what happens then
+	pause      int
+	clearTrick bool
+	pausedMove *engine.Move
+
+	// the agent on turn is choosing its move since thinkingSince and
+	// delivers it to agentMoves
+	agentMoves    chan agentMove
+	thinking      bool
+	thinkingSince time.Time
+
+	// ctx is cancelled when the deal is closed
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// spectators are shown the moves played in the deal, which is the
+	// deal with the passed number in the series shown to them
+	spectators Spectators
+	number     int
+}
+
+// New creates a deal from the record, playing its moves first. The agents
+// are indexed by player and a nil agent means that the player is the user.
+func New(record *engine.Record, agents [2]santase.Agent) (*Deal, error) {
+	state, views, err := record.Replay(agents)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Deal{
+		state:      state,
+		record:     record,
+		agents:     agents,
+		views:      views,
+		cardPlayed: state.CardPlayed(),
+		leader:     state.Leader(),
+		agentMoves: make(chan agentMove, 1),
+		ctx:        ctx,
+		cancel:     cancel,
+	}, nil
+}
+
+// NewRemote creates a deal played over a network, which starts from the
+// state the user sees. The user plays as PlayerOne and the remote plays
+// the moves of PlayerTwo.
+func NewRemote(state engine.State, remote Remote) *Deal {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Deal{
+		state:      state,
+		remote:     remote,
+		cardPlayed: state.CardPlayed(),
+		leader:     state.Leader(),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	d.startThinking()
+	return d
+}
+
+// NewSpectator creates a deal watched over a network by a spectator, who
+// sees it from the seat of PlayerOne. The remote plays the moves of both
+// players.
+func NewSpectator(state engine.State, remote Remote) *Deal {
+	d := NewRemote(state, remote)
+	d.spectator = true
+	d.startThinking()
+	return d
+}
+
+// Close abandons the deal, for example when the user starts a new one. An
+// agent that is choosing its move is interrupted if it implements
+// engine.ContextAgent; the move of any other agent is dropped when it is
+// chosen. The deal does not change after it is closed.
+func (d *Deal) Close() {
+	d.cancel()
+	d.thinking = false
+}
+
+// ShowTo shows the deal, which is the one with the passed number in the
+// series shown to the spectators, to them now and after every move. Only
+// a deal that is not played over a network can be shown.
+func (d *Deal) ShowTo(spectators Spectators, number int) {
+	d.spectators = spectators
+	d.number = number
+	spectators.Show(number, d.record, nil)
+}
+
+// State returns the state of the deal.
+func (d *Deal) State() engine.State {
+	return d.state
+}
+
+// Record returns the record of the moves played so far or nil if the deal
+// is played over a network.
+func (d *Deal) Record() *engine.Record {
+	return d.record
+}
+
+// IsRemote returns whether the deal is played over a network. The state
+// of such a deal holds other cards in place of the cards the user cannot
+// see.
+func (d *Deal) IsRemote() bool {
+	return d.remote != nil
+}
+
+// IsAgent returns whether the player is controlled by an agent, which is
+// also the case for the opponent in a deal played over a network and for
+// both players in a deal watched by a spectator.
+func (d *Deal) IsAgent(p engine.Player) bool {
+	return d.agents[p] != nil || (d.remote != nil && (p == engine.PlayerTwo || d.spectator))
+}
+
+// IsOver returns whether the deal is over and the end of its last trick
+// has been shown.
+func (d *Deal) IsOver() bool {
+	return d.state.IsOver() && d.pause == 0
+}
+
+// AwaitsUser returns whether it is the user's turn and the user can play.
+func (d *Deal) AwaitsUser() bool {
+	return d.ctx.Err() == nil && !d.state.IsOver() && d.pause == 0 && !d.waiting && !d.IsAgent(d.state.ToMove())
+}
+
+// Thinking returns whether an agent, or the opponent in a deal played over
+// a network, is choosing its move and for how long it has been thinking.
+func (d *Deal) Thinking() (bool, time.Duration) {
+	if !d.thinking {
+		return false, 0
+	}
+	return true, time.Since(d.thinkingSince)
+}
+
+// Table returns the cards on the table and the player that led the first
+// of them. The cards of a completed trick stay on the table for a while.
+func (d *Deal) Table() (cardPlayed, response *santase.Card, leader engine.Player) {
+	return d.cardPlayed, d.response, d.leader
+}
+
+// ShowTrick puts the cards of a completed trick on the table.
+func (d *Deal) ShowTrick(cards [2]santase.Card, leader engine.Player) {
+	d.cardPlayed, d.response, d.leader = &cards[0], &cards[1], leader
+}
+
+// Revealed returns the cards that stood for cards the user could not see
+// and were revealed in the last frame of a deal played over a network,
+// mapped to the cards they turned out to be.
+func (d *Deal) Revealed() map[santase.Card]santase.Card {
+	return d.revealed
+}
+
+// Announcement returns the points of the marriage announced with the last
+// move or 0 if there was none.
+func (d *Deal) Announcement() int {
+	return d.announcement
+}
+
+// SwitchTrumpCard returns whether the player on turn has switched the
+// trump card with the move they are about to play.
+func (d *Deal) SwitchTrumpCard() bool {
+	return d.switchTrumpCard
+}
+
+// CloseGame returns whether the player on turn has closed the game with the
+// move they are about to play.
+func (d *Deal) CloseGame() bool {
+	return d.closeGame
+}
+
+// Announces returns whether the user has chosen to announce a marriage with
+// the next card they play.
+func (d *Deal) Announces() bool {
+	return d.announce
+}
+
+// ToggleAnnouncement chooses whether the user announces a marriage with the
+// next card they play and returns whether they do. The user can announce
+// only if they hold a marriage.
+func (d *Deal) ToggleAnnouncement() bool {
+	d.announce = !d.announce && d.AwaitsUser() && d.CanAnnounceAny()
+	return d.announce
+}
+
+// CanDeclareSwitchTrumpCard returns whether the user can switch the trump
+// card now.
+func (d *Deal) CanDeclareSwitchTrumpCard() bool {
+	return d.AwaitsUser() && !d.switchTrumpCard && !d.closeGame && d.state.CanSwitchTrumpCard()
+}
+
+// DeclareSwitchTrumpCard switches the trump card with the next move of the
+// user and returns whether it is allowed.
+func (d *Deal) DeclareSwitchTrumpCard() bool {
+	if !d.CanDeclareSwitchTrumpCard() {
+		return false
+	}
+	d.switchTrumpCard = true
+	return true
+}
+
+// CanDeclareClose returns whether the user can close the game now.
+func (d *Deal) CanDeclareClose() bool {
+	return d.AwaitsUser() && !d.closeGame && d.state.CanClose()
+}
+
+// DeclareClose closes the game with the next move of the user and returns
+// whether it is allowed.
+func (d *Deal) DeclareClose() bool {
+	if !d.CanDeclareClose() {
+		return false
+	}
+	d.closeGame = true
+	return true
+}
+
+// Hand returns the hand of the player as it should be shown, including a
+// trump card switch that is about to be played.
+func (d *Deal) Hand(p engine.Player) santase.Hand {
+	hand := d.state.Hand(p)
+	if d.switchTrumpCard && d.state.ToMove() == p {
+		hand.RemoveCard(santase.NewCard(santase.Nine, d.state.Trump()))
+		hand.AddCard(*d.state.TrumpCard())
+	}
+	return hand
+}
+
+// SortedHand returns the cards of Hand sorted by suit and rank.
+func (d *Deal) SortedHand(p engine.Player) []santase.Card {
+	hand := d.Hand(p)
+	cards := hand.ToSlice()
+	sort.Slice(cards, func(i, j int) bool {
+		return cards[i].Suit < cards[j].Suit || (cards[i].Suit == cards[j].Suit && cards[i].Rank < cards[j].Rank)
+	})
+	return cards
+}
+
+// TrumpCard returns the trump card as it should be shown or nil if it has
+// been drawn.
+func (d *Deal) TrumpCard() *santase.Card {
+	if d.switchTrumpCard {
+		nineTrump := santase.NewCard(santase.Nine, d.state.Trump())
+		return &nineTrump
+	}
+	return d.state.TrumpCard()
+}
+
+// IsClosed returns whether the game is closed or is about to be closed.
+func (d *Deal) IsClosed() bool {
+	return d.state.IsClosed() || d.closeGame
+}
+
+// CanAnnounce returns whether the player on turn can announce a marriage
+// with the card, taking into account a trump card switch that is about to
+// be played.
+func (d *Deal) CanAnnounce(card santase.Card) bool {
+	if (card.Rank != santase.Queen && card.Rank != santase.King) || d.state.CardPlayed() != nil {
+		return false
+	}
+
+	var other santase.Card
+	if card.Rank == santase.Queen {
+		other = santase.NewCard(santase.King, card.Suit)
+	} else {
+		other = santase.NewCard(santase.Queen, card.Suit)
+	}
+
+	hand := d.Hand(d.state.ToMove())
+	return hand.HasCard(card) && hand.HasCard(other)
+}
+
+// CanAnnounceAny returns whether the player on turn can announce any
+// marriage.
+func (d *Deal) CanAnnounceAny() bool {
+	for card := range d.Hand(d.state.ToMove()) {
+		if d.CanAnnounce(card) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCardLegal returns whether the player on turn can play the card, taking
+// into account a trump card switch that is about to be played.
+func (d *Deal) IsCardLegal(card santase.Card) bool {
+	if d.switchTrumpCard {
+		hand := d.Hand(d.state.ToMove())
+		return hand.HasCard(card)
+	}
+	return d.state.IsCardLegal(card)
+}
+
+// CanClaim returns whether the user can claim 66 now.
+func (d *Deal) CanClaim() bool {
+	return d.AwaitsUser() && !d.state.Rules().AutoClaim && d.state.CanClaim()
+}
+
+// ClaimMove returns the move with which the player on turn claims to have
+// collected 66 points. If they need the points of a marriage they hold to
+// reach 66, the marriage is announced together with the claim.
+func (d *Deal) ClaimMove() engine.Move {
+	p := d.state.ToMove()
+	move := engine.Move{Claim: true}
+
+	score := d.state.Score(p)
+	if score >= 66 || d.state.Tricks(p) == 0 {
+		return move
+	}
+
+	hand := d.Hand(p)
+	for card := range hand {
+		if card.Rank == santase.Queen && d.CanAnnounce(card) {
+			points := 20
+			if card.Suit == d.state.Trump() {
+				points = 40
+			}
+			if score+points >= 66 {
+				move.Card = card
+				move.IsAnnouncement = true
+			}
+		}
+	}
+	return move
+}
+
+// Play plays the move of the user on turn together with the trump card
+// switch, the closing of the game and the announcement declared before it.
+// The announcement is made only if the card played is part of a marriage.
+func (d *Deal) Play(m engine.Move) ([]engine.Event, error) {
+	if !d.AwaitsUser() {
+		return nil, fmt.Errorf("it is not the user's turn")
+	}
+	m.SwitchTrumpCard = m.SwitchTrumpCard || d.switchTrumpCard
+	m.CloseGame = m.CloseGame || d.closeGame
+	m.IsAnnouncement = m.IsAnnouncement || (d.announce && !m.Claim && d.CanAnnounce(m.Card))
+	if d.remote == nil {
+		return d.apply(m)
+	}
+
+	// the move is shown when the remote has played it
+	if _, _, err := d.state.Apply(m); err != nil {
+		return nil, fmt.Errorf("%v: %w", d.state.ToMove(), err)
+	}
+	d.remote.Play(m)
+	d.waiting = true
+	return nil, nil
+}
+
+// Update advances the deal by a frame: it ends a pause, asks an agent for
+// its move or plays the move an agent has chosen. A deal played over a
+// network shows the next update of the remote instead. It returns the
+// events of the moves played, if any, or an error if an agent has chosen
+// an illegal move.
+func (d *Deal) Update() ([]engine.Event, error) {
+	d.revealed = nil
+	if d.ctx.Err() != nil {
+		return nil, nil
+	}
+
+	if d.pause > 0 {
+		d.pause--
+		if d.pause > 0 {
+			return nil, nil
+		}
+		if d.clearTrick {
+			d.cardPlayed, d.response = nil, nil
+			d.clearTrick = false
+		}
+		if m := d.pausedMove; m != nil {
+			d.pausedMove = nil
+			return d.declare(*m)
+		}
+	}
+
+	if d.remote != nil {
+		return d.receive(), nil
+	}
+	if d.state.IsOver() || !d.IsAgent(d.state.ToMove()) {
+		return nil, nil
+	}
+
+	if !d.thinking {
+		d.think()
+		return nil, nil
+	}
+
+	select {
+	case result := <-d.agentMoves:
+		d.thinking = false
+		if result.err != nil {
+			return nil, result.err
+		}
+		return d.declare(result.move)
+	default:
+		return nil, nil
+	}
+}
+
+// think asks the agent on turn for its move on a separate goroutine. The
+// agent only reads the state and its own view, which are not changed until
+// the move is delivered. The goroutine never blocks on delivering the move,
+// so it ends as soon as the agent returns even if the deal is closed.
+func (d *Deal) think() {
+	p := d.state.ToMove()
+	agent, view, state := d.agents[p], d.views[p], d.state
+	ctx, moves := d.ctx, d.agentMoves
+
+	d.thinking = true
+	d.thinkingSince = time.Now()
+	go func() {
+		var result agentMove
+		defer func() {
+			// santase.Game panics when an agent chooses an illegal move
+			if r := recover(); r != nil {
+				result.err = fmt.Errorf("%v: %v", p, r)
+			}
+			moves <- result
+		}()
+		result.move = engine.AgentMoveContext(ctx, agent, view, state)
+	}()
+}
+
+// declare shows the trump card switch and the closing of the game made
+// with an agent's move one at a time, pausing after each of them, and then
+// plays the move.
+func (d *Deal) declare(m engine.Move) ([]engine.Event, error) {
+	switch {
+	case m.SwitchTrumpCard && !d.switchTrumpCard:
+		d.switchTrumpCard = true
+	case m.CloseGame && !d.closeGame:
+		d.closeGame = true
+	default:
+		return d.apply(m)
+	}
+
+	d.pause = PauseFrames
+	d.pausedMove = &m
+	return nil, nil
+}
+
+// receive shows the next update of the remote, if there is one, and
+// returns its events.
+func (d *Deal) receive() []engine.Event {
+	var u Update
+	select {
+	case u = <-d.remote.Updates():
+	default:
+		return nil
+	}
+
+	d.waiting = false
+	d.revealed = u.Revealed
+	if u.Events == nil {
+		d.show(u.State, nil)
+		d.cardPlayed, d.response, d.leader = u.State.CardPlayed(), nil, u.State.Leader()
+		d.pause, d.clearTrick = 0, false
+	} else {
+		d.show(u.State, u.Events)
+	}
+	d.startThinking()
+	return u.Events
+}
+
+// startThinking shows that the opponent in a deal played over a network
+// is choosing their move if they are on turn, or that either player is in
+// a deal watched by a spectator.
+func (d *Deal) startThinking() {
+	d.thinking = !d.state.IsOver() && (d.state.ToMove() == engine.PlayerTwo || d.spectator)
+	d.thinkingSince = time.Now()
+}
+
+func (d *Deal) apply(m engine.Move) ([]engine.Event, error) {
+	state, events, err := d.state.Apply(m)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", d.state.ToMove(), err)
+	}
+	d.record.Add(m)
+	engine.UpdateAgents(d.agents, d.views, events)
+	d.show(state, events)
+	if d.spectators != nil {
+		d.spectators.Show(d.number, d.record, events)
+	}
+	return events, nil
+}
+
+// show puts the state after a move on the table: the cards played with it
+// are shown and the declarations made before it are cleared.
+func (d *Deal) show(state engine.State, events []engine.Event) {
+	d.state = state
+	d.switchTrumpCard = false
+	d.closeGame = false
+	d.announce = false
+
+	d.announcement = 0
+	for _, e := range events {
+		switch e := e.(type) {
+		case engine.Announced:
+			d.announcement = e.Points
+		case engine.CardPlayed:
+			card := e.Move.Card
+			if d.cardPlayed == nil {
+				d.cardPlayed = &card
+				d.leader = e.Player
+			} else {
+				d.response = &card
+			}
+		case engine.TrickWon:
+			d.pause = PauseFrames
+			d.clearTrick = true
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package table
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	santase "github.com/nvlbg/santase-ai"
+	"github.com/nvlbg/santase-ai/agents/random"
+
+	"github.com/nvlbg/santase-gui/engine"
+)
+
+// playOut advances the deal frame by frame until it is over and returns
+// the events of its moves.
+func playOut(t *testing.T, d *Deal) []engine.Event {
+	t.Helper()
+
+	var events []engine.Event
+	deadline := time.Now().Add(10 * time.Second)
+	for !d.IsOver() {
+		if time.Now().After(deadline) {
+			t.Fatalf("the deal is not over after 10s: %+v", d.State())
+		}
+		if d.AwaitsUser() {
+			t.Fatalf("the deal waits for the user although both players are agents")
+		}
+		more, err := d.Update()
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, more...)
+		if thinking, _ := d.Thinking(); thinking {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	return events
+}
+
+func TestAgentsPlayDeal(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		first := engine.Player(seed % 2)
+		record := engine.NewRecord(engine.NewDeck(rand.New(rand.NewSource(seed))), first, engine.Rules{})
+		d, err := New(record, [2]santase.Agent{random.NewAgent(), random.NewAgent()})
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+
+		events := playOut(t, d)
+		d.Close()
+
+		state := d.State()
+		if !state.IsOver() {
+			t.Fatalf("seed %d: the state of a deal that is over is not over", seed)
+		}
+		if _, ok := events[len(events)-1].(engine.GameOver); !ok {
+			t.Errorf("seed %d: the last event is %#v, want engine.GameOver", seed, events[len(events)-1])
+		}
+
+		// the record holds every move of the deal
+		replayed, _, err := d.Record().Replay([2]santase.Agent{})
+		if err != nil {
+			t.Fatalf("seed %d: replaying the record: %v", seed, err)
+		}
+		if !replayed.IsOver() || replayed.Result() != state.Result() {
+			t.Errorf("seed %d: the record replays to %+v, want %+v", seed, replayed.Result(), state.Result())
+		}
+	}
+}
+
+func TestCloseStopsDeal(t *testing.T) {
+	record := engine.NewRecord(engine.NewDeck(rand.New(rand.NewSource(1))), engine.PlayerOne, engine.Rules{})
+	d, err := New(record, [2]santase.Agent{random.NewAgent(), random.NewAgent()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// start the agent of the first player and abandon the deal while it
+	// may still be choosing its move
+	if _, err := d.Update(); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+
+	for i := 0; i < 10; i++ {
+		time.Sleep(time.Millisecond)
+		events, err := d.Update()
+		if err != nil || events != nil {
+			t.Fatalf("Update() of a closed deal = %v, %v; want no events", events, err)
+		}
+	}
+	if thinking, _ := d.Thinking(); thinking {
+		t.Errorf("a closed deal is thinking")
+	}
+	if d.AwaitsUser() {
+		t.Errorf("a closed deal awaits the user")
+	}
+	if len(d.Record().Moves) != 0 {
+		t.Errorf("a closed deal recorded the moves %v", d.Record().Moves)
+	}
+}
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/engine"
+	"github.com/nvlbg/santase-gui/table"
+)
+
+// tuiLogLines is the number of the most recent events shown under the
+// table.
+const tuiLogLines = 6
+
+// tuiHelp lists the commands of the terminal front end.
+const tuiHelp = `Commands, each followed by Enter:
+  1-6   play the card with the number from your hand
+  QS    play the card; ~#QS+ switches the trump card, closes and announces too
+  m     announce a marriage with the next card
+  t     exchange the nine of trumps for the trump card
+  c     close the game
+  s     claim 66
+  r     replay the current deal for practice; it is not counted
+  n     abandon the match and start a new one
+  save  save the deal to continue it later with --load
+  q     quit
+An empty line starts the next deal once a deal has ended. Moves typed
+before your turn are played when it comes.`
+
+// tui plays a match in a terminal instead of a window. The match is run by
+// a table.Match like in the window, whose deal is advanced
+// table.FramesPerSecond times a second, and the commands of the user are read line by line, so
+// that it works over SSH and with the input piped in as well. The table is
+// printed again whenever it changes.
+type tui struct {
+	match *table.Match
+
+	out io.Writer
+
+	// ansi is whether the output is a terminal in which the screen is
+	// cleared before the table is printed and the red suits are colored
+	ansi bool
+
+	// screen is the table printed last, which is not printed again until
+	// it changes
+	screen string
+
+	// events are the descriptions of the most recent events and message
+	// is the answer to the last command of the user
+	events  []string
+	message string
+
+	// commands holds the commands of the user which wait until they can be
+	// carried out
+	commands []string
+}
+
+func newTUI(match *table.Match, out io.Writer) *tui {
+	return &tui{
+		match: match,
+		out:   out,
+		ansi:  isTerminal(out),
+	}
+}
+
+// startedDeal clears what was shown of the deal that has been replaced
+// and returns the error with which the next one failed to start, if any.
+func (t *tui) startedDeal(err error) error {
+	t.events = nil
+	t.message = ""
+	return err
+}
+
+// run plays the match with the commands read from the input until the user
+// quits. When the input ends the match goes on until the user has to make
+// a move.
+func (t *tui) run(in io.Reader) error {
+	defer t.match.Close()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	ticker := time.NewTicker(time.Second / table.FramesPerSecond)
+	defer ticker.Stop()
+
+	eof := false
+	for {
+		t.draw()
+		for len(t.commands) > 0 && t.ready(t.commands[0]) {
+			command := t.commands[0]
+			t.commands = t.commands[1:]
+			quit, err := t.handle(command)
+			if quit || err != nil {
+				return err
+			}
+			t.draw()
+		}
+		if eof && len(t.commands) == 0 && t.needsUser() {
+			return nil
+		}
+
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				eof, lines = true, nil
+				continue
+			}
+			t.commands = append(t.commands, strings.TrimSpace(line))
+		case <-ticker.C:
+			if err := t.update(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// update advances the deal by a frame and records its result when it ends.
+func (t *tui) update() error {
+	events, err := t.match.Deal().Update()
+	if err != nil {
+		return err
+	}
+	t.showEvents(events)
+	t.match.Update()
+	return nil
+}
+
+// needsUser returns whether the match waits for a command of the user.
+func (t *tui) needsUser() bool {
+	_, recorded := t.match.Result()
+	return recorded || t.isUserMove()
+}
+
+// isUserMove returns whether the user is on turn and can play.
+func (t *tui) isUserMove() bool {
+	d := t.match.Deal()
+	return d.AwaitsUser() && d.State().ToMove() == engine.PlayerOne
+}
+
+// ready returns whether the command can be carried out now. The moves of
+// the user and empty lines wait until the user is on turn or the deal has
+// ended.
+func (t *tui) ready(command string) bool {
+	switch strings.ToLower(command) {
+	case "q", "quit", "?", "h", "help", "n", "r", "save":
+		return true
+	}
+	return t.needsUser()
+}
+
+// handle carries out a command of the user and returns whether they quit.
+func (t *tui) handle(command string) (bool, error) {
+	t.message = ""
+	switch strings.ToLower(command) {
+	case "q", "quit":
+		return true, nil
+	case "?", "h", "help":
+		t.message = tuiHelp
+	case "n":
+		return false, t.startedDeal(t.match.NewGame())
+	case "r":
+		return false, t.startedDeal(t.match.ReplayDeal())
+	case "save":
+		if name, err := t.match.Save(); err != nil {
+			t.message = fmt.Sprintf("Cannot save the game: %v", err)
+		} else {
+			t.message = fmt.Sprintf("Game saved; continue it with --load=%s", name)
+		}
+	case "":
+		if _, recorded := t.match.Result(); recorded {
+			return false, t.startedDeal(t.match.NextDeal())
+		}
+	default:
+		if !t.isUserMove() {
+			t.message = "It is not your turn"
+			return false, nil
+		}
+		t.play(command)
+	}
+	return false, nil
+}
+
+// play makes the move of the user given by the command.
+func (t *tui) play(command string) {
+	d := t.match.Deal()
+	var move engine.Move
+	switch strings.ToLower(command) {
+	case "m":
+		if !d.ToggleAnnouncement() && !d.CanAnnounceAny() {
+			t.message = "You have no marriage to announce"
+		}
+		return
+	case "t":
+		if !d.DeclareSwitchTrumpCard() {
+			t.message = "You cannot exchange the trump card now"
+		}
+		return
+	case "c":
+		if !d.DeclareClose() {
+			t.message = "You cannot close the game now"
+		}
+		return
+	case "s":
+		if !d.CanClaim() {
+			t.message = "You cannot claim 66 now"
+			return
+		}
+		move = d.ClaimMove()
+	default:
+		if i, err := strconv.Atoi(command); err == nil {
+			hand := d.SortedHand(engine.PlayerOne)
+			if i < 1 || i > len(hand) {
+				t.message = fmt.Sprintf("Choose a card from 1 to %d", len(hand))
+				return
+			}
+			move = engine.Move{Card: hand[i-1]}
+			break
+		}
+		m, err := engine.ParseMove(strings.ToUpper(command))
+		if err != nil {
+			t.message = fmt.Sprintf("Unknown command %q; type ? for help", command)
+			return
+		}
+		move = m
+	}
+
+	events, err := d.Play(move)
+	if err != nil {
+		t.message = fmt.Sprintf("You cannot play that: %v", err)
+		return
+	}
+	t.showEvents(events)
+}
+
+// showEvents describes the events of the moves that have just been played
+// under the table.
+func (t *tui) showEvents(events []engine.Event) {
+	for _, e := range events {
+		var line string
+		switch e := e.(type) {
+		case engine.TrumpSwitched:
+			line = fmt.Sprintf("%s the nine of trumps for %s", who(e.Player, "You exchange", "Opponent exchanges"), t.card(e.TrumpCard))
+		case engine.GameClosed:
+			line = who(e.Player, "You close", "Opponent closes") + " the game"
+		case engine.Announced:
+			line = fmt.Sprintf("%s %d in %s", who(e.Player, "You announce", "Opponent announces"), e.Points, t.suit(e.Suit))
+			if e.Pending {
+				line += ", counted after the first trick"
+			}
+		case engine.CardPlayed:
+			line = fmt.Sprintf("%s %s", who(e.Player, "You play", "Opponent plays"), t.card(e.Move.Card))
+		case engine.TrickWon:
+			line = fmt.Sprintf("%s the trick for %d points", who(e.Player, "You take", "Opponent takes"), e.Points)
+		case engine.LastTrickBonusWon:
+			line = fmt.Sprintf("%s %d points for the last trick", who(e.Player, "You get", "Opponent gets"), e.Points)
+		case engine.CardDrawn:
+			line = "Opponent draws a card"
+			if e.Player == engine.PlayerOne {
+				line = fmt.Sprintf("You draw %s", t.card(e.Card))
+			}
+		case engine.Claimed:
+			line = who(e.Player, "You claim", "Opponent claims") + " 66"
+			if !e.Valid {
+				line += " falsely"
+			}
+		default:
+			continue
+		}
+		t.events = append(t.events, line)
+	}
+	if len(t.events) > tuiLogLines {
+		t.events = t.events[len(t.events)-tuiLogLines:]
+	}
+}
+
+// draw prints the table, or the scoreboard when a deal has ended, if it
+// has changed since it was printed last.
+func (t *tui) draw() {
+	var b strings.Builder
+	if _, recorded := t.match.Result(); recorded {
+		t.drawScoreboard(&b)
+	} else {
+		t.drawTable(&b)
+	}
+	if t.message != "" {
+		fmt.Fprintf(&b, "\n%s\n", t.message)
+	}
+
+	screen := b.String()
+	if screen == t.screen {
+		return
+	}
+	t.screen = screen
+	if t.ansi {
+		// move to the top left corner and clear the screen
+		fmt.Fprint(t.out, "\x1b[H\x1b[2J")
+	} else {
+		fmt.Fprintln(t.out)
+	}
+	fmt.Fprint(t.out, screen)
+}
+
+func (t *tui) drawTable(b *strings.Builder) {
+	d := t.match.Deal()
+	state := d.State()
+	match := t.match.State()
+
+	fmt.Fprintf(b, "Santase - deal %d, game points: you %d, opponent %d\n\n", len(match.Deals())+1,
+		match.GamePoints(engine.PlayerOne), match.GamePoints(engine.PlayerTwo))
+
+	opponent := d.Hand(engine.PlayerTwo)
+	fmt.Fprintf(b, "%s\n", strings.TrimSpace("Opponent  "+strings.Repeat("▒▒ ", len(opponent))))
+	if marriages := state.Marriages(engine.PlayerTwo); len(marriages) > 0 {
+		fmt.Fprintf(b, "          marriages %s\n", t.suits(marriages))
+	}
+	b.WriteString("\n")
+
+	talon := len(state.Stack())
+	trump := t.suit(state.Trump())
+	if card := d.TrumpCard(); card != nil {
+		talon++
+		trump = t.card(*card)
+	}
+	cards := fmt.Sprintf("%d cards", talon)
+	if talon == 1 {
+		cards = "1 card"
+	}
+	if d.IsClosed() {
+		cards += ", closed"
+	}
+	fmt.Fprintf(b, "Trump     %s, talon %s\n", trump, cards)
+
+	var trick []string
+	cardPlayed, response, leader := d.Table()
+	if cardPlayed != nil {
+		trick = append(trick, fmt.Sprintf("%s %s", who(leader, "you", "opponent"), t.card(*cardPlayed)))
+	}
+	if response != nil {
+		trick = append(trick, fmt.Sprintf("%s %s", who(leader.Other(), "you", "opponent"), t.card(*response)))
+	}
+	fmt.Fprintf(b, "%s\n\n", strings.TrimSpace("Table     "+strings.Join(trick, ", ")))
+
+	var hand []string
+	for i, card := range d.SortedHand(engine.PlayerOne) {
+		hand = append(hand, fmt.Sprintf("%d:%s", i+1, t.card(card)))
+	}
+	fmt.Fprintf(b, "%s\n", strings.TrimSpace("You       "+strings.Join(hand, "  ")))
+	score := fmt.Sprintf("%d points", state.Score(engine.PlayerOne))
+	if pending := state.PendingScore(engine.PlayerOne); pending > 0 {
+		score += fmt.Sprintf(" (+%d after your first trick)", pending)
+	}
+	if marriages := state.Marriages(engine.PlayerOne); len(marriages) > 0 {
+		score += ", marriages " + t.suits(marriages)
+	}
+	fmt.Fprintf(b, "          %s\n\n", score)
+
+	for _, line := range t.events {
+		fmt.Fprintf(b, "  %s\n", line)
+	}
+	b.WriteString("\n")
+
+	thinking, _ := d.Thinking()
+	switch {
+	case t.isUserMove():
+		var declared []string
+		if d.SwitchTrumpCard() {
+			declared = append(declared, "exchanging the trump card")
+		}
+		if d.CloseGame() {
+			declared = append(declared, "closing")
+		}
+		if d.Announces() {
+			declared = append(declared, "announcing")
+		}
+		prompt := "Your turn"
+		if len(declared) > 0 {
+			prompt += " (" + strings.Join(declared, ", ") + ")"
+		}
+		fmt.Fprintf(b, "%s - play a card by its number or type ? for help\n> ", prompt)
+	case thinking && state.ToMove() == engine.PlayerOne:
+		b.WriteString("Your agent is thinking...\n")
+	case thinking:
+		b.WriteString("Opponent is thinking...\n")
+	}
+}
+
+func (t *tui) drawScoreboard(b *strings.Builder) {
+	match := t.match.State()
+	lastDeal, _ := t.match.Result()
+
+	var message string
+	switch {
+	case match.IsOver() && match.Winner() == engine.PlayerOne:
+		message = "You win the match!"
+	case match.IsOver():
+		message = "You lose the match!"
+	case lastDeal.Winner == engine.PlayerOne:
+		message = "You win!"
+	default:
+		message = "You lose!"
+	}
+
+	points := fmt.Sprintf("+%d game points", lastDeal.GamePoints)
+	if lastDeal.GamePoints == 1 {
+		points = "+1 game point"
+	}
+	if !t.match.Counted() {
+		points = "Practice deal - not counted"
+	}
+	fmt.Fprintf(b, "%s %s\n", message, points)
+
+	state := t.match.Deal().State()
+	if p, claimed := state.ClaimedBy(); claimed && !state.IsClaimValid() {
+		fmt.Fprintf(b, "%s 66 falsely\n", who(p, "You claimed", "Opponent claimed"))
+	}
+	b.WriteString("\n")
+
+	fmt.Fprintf(b, "%-6s %9s %6s %9s\n", "Deal", "Points", "You", "Opponent")
+	for _, row := range t.match.Scoreboard(0) {
+		fmt.Fprintf(b, "%-6d %4d:%-4d %6d %9d\n",
+			row.Deal, row.Scores[engine.PlayerOne], row.Scores[engine.PlayerTwo],
+			row.GamePoints[engine.PlayerOne], row.GamePoints[engine.PlayerTwo])
+	}
+	fmt.Fprintf(b, "%-6s %9s %6d %9d\n\n", "Total", "",
+		match.GamePoints(engine.PlayerOne), match.GamePoints(engine.PlayerTwo))
+
+	next := "the next deal"
+	if match.IsOver() {
+		next = "a new match"
+	}
+	fmt.Fprintf(b, "Press Enter for %s, r to replay this deal (seed %d) or q to quit\n> ", next, t.match.Seed())
+}
+
+// card returns the card with its suit symbol, in red for hearts and
+// diamonds if the terminal shows colors.
+func (t *tui) card(card santase.Card) string {
+	if t.ansi && (card.Suit == santase.Hearts || card.Suit == santase.Diamonds) {
+		return "\x1b[31m" + card.String() + "\x1b[0m"
+	}
+	return card.String()
+}
+
+// suit returns the symbol of the suit like card.
+func (t *tui) suit(suit santase.Suit) string {
+	if t.ansi && (suit == santase.Hearts || suit == santase.Diamonds) {
+		return "\x1b[31m" + suit.String() + "\x1b[0m"
+	}
+	return suit.String()
+}
+
+func (t *tui) suits(suits []santase.Suit) string {
+	s := make([]string, len(suits))
+	for i, suit := range suits {
+		s[i] = t.suit(suit)
+	}
+	return strings.Join(s, " ")
+}
+
+// who returns the text for the user if the player is PlayerOne or else the
+// text for their opponent.
+func who(p engine.Player, you, opponent string) string {
+	if p == engine.PlayerOne {
+		return you
+	}
+	return opponent
+}
+
+// isTerminal returns whether the output is a terminal which understands
+// ANSI escape sequences.
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok || os.Getenv("TERM") == "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// runTUI plays a match against the opponent agent in the terminal.
+func runTUI(opponentAgent santase.Agent, playerAgent *santase.Agent, opts settings) error {
+	match, err := startMatch(opponentAgent, playerAgent, opts)
+	if err != nil {
+		return err
+	}
+	return newTUI(match, os.Stdout).run(os.Stdin)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	santase "github.com/nvlbg/santase-ai"
+
+	"github.com/nvlbg/santase-gui/engine"
+	"github.com/nvlbg/santase-gui/network"
+	"github.com/nvlbg/santase-gui/table"
+)
+
+// watch runs the watch subcommand which prints the events of a match
+// watched over the network without opening a window, for example to
+// follow a match between two bots.
+func watch(args []string) {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	hands := flags.Bool("hands", false, "show both hands and the cards drawn")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of %s watch [--hands] address:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	mode := network.SpectatePublic
+	if *hands {
+		mode = network.SpectateHands
+	}
+	client, err := network.Watch(flags.Arg(0), mode)
+	if err != nil {
+		log.Fatalf("cannot watch %s: %v", flags.Arg(0), err)
+	}
+	defer client.Close()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var deal *network.Deal
+	var updates <-chan table.Update
+	for {
+		select {
+		case d := <-client.Deals():
+			// the updates of the last deal arrive before the next deal
+			// starts
+			for done := false; !done; {
+				select {
+				case u := <-updates:
+					printEvents(deal, u)
+				default:
+					done = true
+				}
+			}
+			deal, updates = d, d.Updates()
+			printDeal(d, *hands)
+		case u := <-updates:
+			printEvents(deal, u)
+		case <-ticker.C:
+			if err := client.Err(); err != nil {
+				log.Fatalf("disconnected: %v", err)
+			}
+		}
+	}
+}
+
+// printDeal prints the start of the deal, or where it stands when the
+// spectator joins it while it is being played.
+func printDeal(d *network.Deal, hands bool) {
+	state := d.State()
+	trump := engine.FormatSuit(state.Trump())
+	if card := state.TrumpCard(); card != nil {
+		trump = engine.FormatCard(*card)
+	}
+	fmt.Printf("deal %d: trump %s, %d cards in the stack, %v to move\n", d.Number(), trump, len(state.Stack()), state.ToMove())
+	if !hands {
+		return
+	}
+	for _, p := range []engine.Player{engine.PlayerOne, engine.PlayerTwo} {
+		fmt.Printf("deal %d: %v holds %s\n", d.Number(), p, formatCards(state.SortedHand(p)))
+	}
+}
+
+// printEvents prints a line for each event of the update.
+func printEvents(d *network.Deal, u table.Update) {
+	for _, e := range u.Events {
+		var line string
+		switch e := e.(type) {
+		case engine.TrumpSwitched:
+			line = fmt.Sprintf("%v switches the trump card %s", e.Player, engine.FormatCard(e.TrumpCard))
+		case engine.GameClosed:
+			line = fmt.Sprintf("%v closes the game", e.Player)
+		case engine.Announced:
+			line = fmt.Sprintf("%v announces %d in %s", e.Player, e.Points, engine.FormatSuit(e.Suit))
+		case engine.CardPlayed:
+			line = fmt.Sprintf("%v plays %s", e.Player, engine.FormatCard(e.Move.Card))
+		case engine.TrickWon:
+			line = fmt.Sprintf("%v takes %s for %d points", e.Player, formatCards(e.Cards[:]), e.Points)
+		case engine.LastTrickBonusWon:
+			line = fmt.Sprintf("%v gets %d points for the last trick", e.Player, e.Points)
+		case engine.CardDrawn:
+			line = fmt.Sprintf("%v draws %s", e.Player, engine.FormatCard(e.Card))
+		case engine.Claimed:
+			line = fmt.Sprintf("%v claims 66 falsely", e.Player)
+			if e.Valid {
+				line = fmt.Sprintf("%v claims 66", e.Player)
+			}
+		case engine.GameOver:
+			result := u.State.Result()
+			points := fmt.Sprintf("%d game points", result.GamePoints)
+			if result.GamePoints == 1 {
+				points = "1 game point"
+			}
+			line = fmt.Sprintf("%v wins with %s (%d:%d)", e.Winner, points,
+				result.Scores[engine.PlayerOne], result.Scores[engine.PlayerTwo])
+		default:
+			continue
+		}
+		fmt.Printf("deal %d: %s\n", d.Number(), line)
+	}
+}
+
+// formatCards returns the cards separated by spaces.
+func formatCards(cards []santase.Card) string {
+	s := make([]string, len(cards))
+	for i, card := range cards {
+		s[i] = engine.FormatCard(card)
+	}
+	return strings.Join(s, " ")
+}